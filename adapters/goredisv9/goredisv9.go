@@ -0,0 +1,194 @@
+// Package goredisv9 implements the redislock.Backend interface and its
+// optional interfaces for github.com/redis/go-redis/v9.
+//
+//	locker := redislock.New(goredisv9.NewRedisLockClient(rdb))
+package goredisv9
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisLockClient struct {
+	client     redis.UniversalClient
+	luaRefresh *redis.Script
+	luaPttl    *redis.Script
+	luaRelease *redis.Script
+	scripts    sync.Map
+}
+
+// NewRedisLockClient accepts a *redis.Client, *redis.ClusterClient or any
+// other redis.UniversalClient.
+func NewRedisLockClient(client redis.UniversalClient) *RedisLockClient {
+	return &RedisLockClient{
+		client:     client,
+		luaRefresh: redis.NewScript(redislock.LuaRefreshScript),
+		luaPttl:    redis.NewScript(redislock.LuaPTTLScript),
+		luaRelease: redis.NewScript(redislock.LuaReleaseScript),
+	}
+}
+
+func (r *RedisLockClient) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(context.Background(), key, value, ttl).Result()
+}
+
+func (r *RedisLockClient) Refresh(key, value string, ttl string) error {
+	status, err := r.luaRefresh.Run(context.Background(), r.client, []string{key}, value, ttl).Result()
+	if err != nil {
+		return err
+	} else if status == int64(1) {
+		return nil
+	}
+	return redislock.ErrNotObtained
+}
+
+func (r *RedisLockClient) Release(key, value string) error {
+	res, err := r.luaRelease.Run(context.Background(), r.client, []string{key}, value).Result()
+	if err == redis.Nil {
+		return redislock.ErrLockNotHeld
+	} else if err != nil {
+		return err
+	}
+
+	if i, ok := res.(int64); !ok || i != 1 {
+		return redislock.ErrLockNotHeld
+	}
+	return nil
+}
+
+func (r *RedisLockClient) TTL(key, value string) (int64, error) {
+	res, err := r.luaPttl.Run(context.Background(), r.client, []string{key}, value).Result()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	} else if res == int64(-3) {
+		return 0, redislock.ErrLockNotHeld
+	}
+	return res.(int64), nil
+}
+
+// Scan returns all keys matching the glob-style pattern, none for an empty
+// pattern. On a cluster all master nodes are scanned.
+func (r *RedisLockClient) Scan(match string) ([]string, error) {
+	//without MATCH the whole keyspace would be scanned
+	if match == "" {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+
+	cluster, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return scan(ctx, r.client, match)
+	}
+
+	var mu sync.Mutex
+	var keys []string
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+		res, err := scan(ctx, client, match)
+		mu.Lock()
+		keys = append(keys, res...)
+		mu.Unlock()
+		return err
+	})
+	return keys, err
+}
+
+func scan(ctx context.Context, client redis.Cmdable, match string) ([]string, error) {
+	var keys []string
+	var cursor uint64
+	for {
+		res, next, err := client.Scan(ctx, cursor, match, 100).Result()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, res...)
+
+		if cursor = next; cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+func (r *RedisLockClient) Get(key string) (string, error) {
+	res, err := r.client.Get(context.Background(), key).Result()
+	if err == redis.Nil {
+		return "", nil
+	} else if err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		//other kinds of locks are not stored as string
+		return "", nil
+	}
+	return res, err
+}
+
+func (r *RedisLockClient) Info(section string) (string, error) {
+	return r.client.Info(context.Background(), section).Result()
+}
+
+func (r *RedisLockClient) ConfigGet(parameter string) (string, error) {
+	res, err := r.client.ConfigGet(context.Background(), parameter).Result()
+	if err != nil {
+		return "", err
+	}
+	return res[parameter], nil
+}
+
+func (r *RedisLockClient) RunScript(script *redislock.Script, keys []string, args ...string) (interface{}, error) {
+	s, ok := r.scripts.Load(script.Name)
+	if !ok {
+		s, _ = r.scripts.LoadOrStore(script.Name, redis.NewScript(script.Source))
+	}
+
+	argv := make([]interface{}, len(args))
+	for i, arg := range args {
+		argv[i] = arg
+	}
+
+	res, err := s.(*redis.Script).Run(context.Background(), r.client, keys, argv...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return res, err
+}
+
+// PSubscribe subscribes to the patterns on a dedicated connection.
+func (r *RedisLockClient) PSubscribe(patterns ...string) (<-chan struct{}, func() error, error) {
+	ctx := context.Background()
+	ch := make(chan struct{}, 1)
+
+	ps := r.client.PSubscribe(ctx, patterns...)
+	for n := 0; n < len(patterns); {
+		msg, err := ps.Receive(ctx)
+		if err != nil {
+			_ = ps.Close()
+			return nil, nil, err
+		}
+
+		switch msg.(type) {
+		case *redis.Subscription:
+			n++
+		case *redis.Message:
+			notify(ch)
+		}
+	}
+
+	go func() {
+		for range ps.Channel() {
+			notify(ch)
+		}
+	}()
+	return ch, ps.Close, nil
+}
+
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,1283 @@
+package goredisv9_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/dineshgowda24/redislock/adapters/goredisv9"
+	"github.com/dineshgowda24/redislock/local"
+	"github.com/dineshgowda24/redislock/locktest"
+	"github.com/dineshgowda24/redislock/mock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+)
+
+const lockKey = "__bsm_redislock_unit_test__"
+
+var _ = Describe("Client", func() {
+	var subject *redislock.Client
+
+	BeforeEach(func() {
+		subject = redislock.New(redisLockClient)
+	})
+
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	It("should obtain once with TTL", func() {
+		lock1, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock1.Token()).To(HaveLen(22))
+		Expect(lock1.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		defer lock1.Release()
+
+		_, err = subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+		Expect(lock1.Release()).To(Succeed())
+
+		lock2, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock2.IsHeld(ctx)).To(BeTrue())
+		Expect(lock2.Release()).To(Succeed())
+	})
+
+	It("should obtain through short-cut", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support custom metadata", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Hour, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("my-data"))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support structured metadata", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, &redislock.Options{
+			Metadata:    "my-data",
+			MetadataMap: map[string]string{"host": "worker-1", "job": "42"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(HaveLen(22))
+		Expect(lock.Metadata()).To(Equal("my-data"))
+		Expect(lock.MetadataValue("host")).To(Equal("worker-1"))
+		Expect(lock.MetadataValue("other")).To(BeEmpty())
+		Expect(lock.MetadataMap()).To(Equal(map[string]string{"host": "worker-1", "job": "42"}))
+
+		info, err := subject.Inspect(lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Token).To(Equal(lock.Token()))
+		Expect(info.Metadata).To(Equal("my-data"))
+		Expect(info.MetadataMap).To(Equal(map[string]string{"host": "worker-1", "job": "42"}))
+		Expect(lock.Release()).To(Succeed())
+
+		lock, err = subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal(lock.Token()))
+		Expect(lock.Metadata()).To(BeEmpty())
+		Expect(lock.MetadataMap()).To(BeNil())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support custom tokens", func() {
+		opt := &redislock.Options{
+			Metadata:       "my-data",
+			TokenGenerator: func() (string, error) { return "worker-1", nil },
+		}
+		lock, err := subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(Equal("worker-1"))
+		Expect(lock.Metadata()).To(Equal("my-data"))
+		_, err = subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		// resume after a restart
+		resumed, err := redislock.New(redisLockClient).LockFromToken(lockKey, "worker-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(resumed.Release()).To(Succeed())
+
+		_, err = subject.Obtain(lockKey, time.Minute, &redislock.Options{
+			TokenGenerator: func() (string, error) { return "", nil },
+		})
+		Expect(err).To(HaveOccurred())
+
+		id, err := redislock.NewOwnerID()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(strings.Split(id, ":")).To(HaveLen(3))
+		Expect(redislock.NewOwnerID()).NotTo(Equal(id))
+	})
+
+	It("should scope reentrant locks to the owner of the context", func() {
+		job1 := redislock.ContextWithOwner(ctx, "job-1")
+		outer, err := subject.Obtain(lockKey, time.Minute, &redislock.Options{Reentrant: true, Context: job1})
+		Expect(err).NotTo(HaveOccurred())
+		inner, err := subject.Obtain(lockKey, time.Minute, &redislock.Options{Reentrant: true, Context: job1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.Token()).To(Equal(outer.Token()))
+
+		_, err = subject.Obtain(lockKey, time.Minute, &redislock.Options{Reentrant: true, Context: redislock.ContextWithOwner(ctx, "job-2")})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, &redislock.Options{Reentrant: true})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(inner.Release()).To(Succeed())
+		Expect(outer.Release()).To(Succeed())
+	})
+
+	It("should share reentrant locks between clients with the same owner ID", func() {
+		opt := &redislock.Options{Reentrant: true}
+		lock, err := redislock.New(redisLockClient, redislock.WithOwnerID("worker-1")).Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Token()).To(Equal("worker-1"))
+
+		_, err = subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		resumed, err := redislock.New(redisLockClient, redislock.WithOwnerID("worker-1")).Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resumed.Release()).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should refresh", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should fail to release if expired", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+		_, err = lock.TTL()
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock.IsHeld(ctx)).To(BeFalse())
+		Expect(lock.Release()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should fail to release if ontained by someone else", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should fail to refresh if expired", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+		Expect(lock.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should enforce refresh limits", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Minute, &redislock.Options{MaxRefreshes: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(time.Minute, nil)).To(Succeed())
+		Expect(lock.Refresh(time.Minute, nil)).To(Succeed())
+		Expect(lock.Refresh(time.Minute, nil)).To(MatchError(redislock.ErrRefreshLimit))
+		Expect(lock.Release()).To(Succeed())
+
+		lock, err = redislock.Obtain(redisLockClient, lockKey, time.Minute, &redislock.Options{MaxExtension: 30 * time.Second})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(time.Minute, nil)).To(Succeed())
+		Expect(lock.Refresh(time.Minute, nil)).To(Succeed())
+		Expect(lock.Refresh(90*time.Second, nil)).To(MatchError(redislock.ErrRefreshLimit))
+		Expect(lock.Refresh(80*time.Second, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should auto refresh beyond the obtain context", func() {
+		obtainCtx, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+		defer cancel()
+
+		lock, err := subject.Obtain(lockKey, 60*time.Millisecond, &redislock.Options{AutoRefresh: true, Context: obtainCtx})
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(lock.Lost(), 150*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lock.TTL()).To(BeNumerically(">", 0))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should stop auto refreshing when the watchdog context is cancelled", func() {
+		watchdogCtx, cancel := context.WithCancel(ctx)
+		lock, err := subject.Obtain(lockKey, 60*time.Millisecond, &redislock.Options{AutoRefresh: true, WatchdogContext: watchdogCtx})
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(lock.Lost(), 100*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lock.TTL()).To(BeNumerically(">", 0))
+
+		cancel()
+		Eventually(func() (bool, error) { return lock.IsHeld(ctx) }, time.Second, 10*time.Millisecond).Should(BeFalse())
+		Expect(lock.Lost()).NotTo(BeClosed())
+	})
+
+	It("should measure extensions from the original TTL", func() {
+		lock, err := subject.Obtain(lockKey, 90*time.Millisecond, &redislock.Options{AutoRefresh: true, MaxExtension: 180 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release()
+
+		Consistently(lock.Lost(), 150*time.Millisecond).ShouldNot(BeClosed())
+		Expect(lock.TTL()).To(BeNumerically(">", 0))
+		Eventually(lock.Lost()).Should(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrRefreshLimit))
+	})
+
+	It("should auto refresh", func() {
+		lock, err := subject.Obtain(lockKey, 30*time.Millisecond, &redislock.Options{AutoRefresh: true})
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.TTL()).To(BeNumerically(">", 0))
+		Expect(lock.Err()).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+		Consistently(lock.Lost(), 50*time.Millisecond).ShouldNot(BeClosed())
+
+		lock, err = subject.Obtain(lockKey, 30*time.Millisecond, &redislock.Options{AutoRefresh: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		Eventually(lock.Lost()).Should(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrNotObtained))
+		Expect(subject.HeldLocks()).To(BeEmpty())
+
+		lock, err = subject.Obtain(lockKey, 30*time.Millisecond, &redislock.Options{AutoRefresh: true, MaxRefreshes: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(lock.Lost()).Should(BeClosed())
+		Expect(lock.Err()).To(MatchError(redislock.ErrRefreshLimit))
+
+		lock, err = subject.Obtain(lockKey+"x", time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Lost()).To(BeNil())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support reentrant locks", func() {
+		opt := &redislock.Options{Reentrant: true}
+		outer, err := subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		inner, err := subject.Obtain(lockKey, time.Second, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.Token()).To(Equal(outer.Token()))
+		Expect(inner.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = redislock.New(redisLockClient).Obtain(lockKey, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(inner.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(inner.Release()).To(Succeed())
+		Expect(inner.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(outer.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(outer.Release()).To(Succeed())
+		Expect(outer.IsHeld(ctx)).To(BeFalse())
+
+		lock, err := redislock.New(redisLockClient).Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support fence tokens", func() {
+		defer redisClient.Del(ctx, subject.FenceKey(lockKey))
+
+		opt := &redislock.Options{Fence: true}
+		lock, err := subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.FenceToken()).To(Equal(int64(1)))
+		Expect(lock.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+
+		lock, err = subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.FenceToken()).To(Equal(int64(2)))
+		Expect(lock.Release()).To(Succeed())
+
+		lock, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.FenceToken()).To(BeZero())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support read/write locks", func() {
+		r1, err := subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := subject.ObtainRead(lockKey, time.Hour, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r2.Metadata()).To(Equal("my-data"))
+		Expect(r1.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(r2.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(r1.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(r1.Release()).To(Succeed())
+		Expect(r1.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(r1.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(r2.Release()).To(Succeed())
+
+		w, err := subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		_, err = subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(w.Release()).To(Succeed())
+		Expect(w.IsHeld(ctx)).To(BeFalse())
+	})
+
+	It("should expire readers individually", func() {
+		r1, err := subject.ObtainRead(lockKey, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(50 * time.Millisecond)
+
+		Expect(r1.IsHeld(ctx)).To(BeFalse())
+		Expect(r2.Release()).To(Succeed())
+		w, err := subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Release()).To(Succeed())
+	})
+
+	It("should support semaphores", func() {
+		s1, err := subject.ObtainSemaphore(lockKey, 2, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		s2, err := subject.ObtainSemaphore(lockKey, 2, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s1.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(s2.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.ObtainSemaphore(lockKey, 2, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.ObtainSemaphore(lockKey, 0, time.Minute, nil)
+		Expect(err).To(HaveOccurred())
+
+		Expect(s1.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(s1.Release()).To(Succeed())
+		Expect(s1.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		s3, err := subject.ObtainSemaphore(lockKey, 2, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(50 * time.Millisecond)
+
+		Expect(s3.IsHeld(ctx)).To(BeFalse())
+		s4, err := subject.ObtainSemaphore(lockKey, 2, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s4.Release()).To(Succeed())
+		Expect(s2.Release()).To(Succeed())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+	})
+
+	It("should wake up waiters on release", func() {
+		client := redislock.New(redisLockClient, redislock.WithReleaseNotifications())
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		obtained := make(chan *redislock.Lock, 1)
+		go func() {
+			defer GinkgoRecover()
+			lock, err := client.Obtain(lockKey, time.Hour, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(time.Minute),
+				ObtainTimeout: time.Minute,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			obtained <- lock
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.Release()).To(Succeed())
+		Eventually(obtained, time.Second).Should(Receive(&lock))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should run callbacks while holding the lock", func() {
+		err := subject.Do(context.Background(), lockKey, 30*time.Millisecond, nil, func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			Expect(ctx.Err()).NotTo(HaveOccurred())
+			_, err := subject.Obtain(lockKey, time.Minute, nil)
+			Expect(err).To(MatchError(redislock.ErrNotObtained))
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(0)))
+
+		lock, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		err = subject.Do(context.Background(), lockKey, time.Minute, nil, func(context.Context) error { return nil })
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Release()).To(Succeed())
+
+		err = subject.Do(context.Background(), lockKey, time.Minute, nil, func(context.Context) error { return context.DeadlineExceeded })
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+	})
+
+	It("should cancel callbacks when the lock is lost", func() {
+		err := subject.Do(context.Background(), lockKey, 30*time.Millisecond, nil, func(ctx context.Context) error {
+			Expect(redisClient.Del(context.Background(), lockKey).Err()).To(Succeed())
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		Expect(err).To(MatchError(redislock.ErrLockLost))
+		Expect(err).To(MatchError(context.Canceled))
+
+		err = subject.Do(context.Background(), lockKey, time.Minute, nil, func(context.Context) error {
+			return redisClient.Del(context.Background(), lockKey).Err()
+		})
+		Expect(err).To(Equal(redislock.ErrLockLost))
+	})
+
+	It("should report held locks", func() {
+		reports := make(chan []redislock.HeldLock, 1)
+		client := redislock.New(redisLockClient, redislock.WithHeldLocksReport(10*time.Millisecond, func(held []redislock.HeldLock) {
+			select {
+			case reports <- held:
+			default:
+			}
+		}))
+		defer client.Close()
+
+		lock, err := client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+
+		var held []redislock.HeldLock
+		Eventually(reports).Should(Receive(&held))
+		Expect(held).To(HaveLen(1))
+		Expect(held[0].Key).To(Equal(lockKey))
+		Expect(held[0].TTL).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(held[0].Refreshes).To(Equal(1))
+
+		Expect(lock.Release()).To(Succeed())
+		Expect(client.HeldLocks()).To(BeEmpty())
+	})
+
+	It("should apply the namespace to pause keys and reports", func() {
+		pauseKey := lockKey + ":__paused"
+		defer redisClient.Del(ctx, pauseKey)
+
+		client := redislock.New(redisLockClient, redislock.WithKeyPrefix(lockKey+":"), redislock.WithPauseKey(redislock.DefaultPauseKey))
+		Expect(client.Pause(time.Minute)).To(Succeed())
+		Expect(redisClient.Exists(ctx, pauseKey).Val()).To(Equal(int64(1)))
+		_, err := client.Obtain("job", time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrLockingPaused))
+		Expect(client.Keys("*")).To(BeEmpty())
+		Expect(client.Sweep(redislock.SweepOptions{
+			Match:    "*",
+			Orphaned: func(redislock.SweptLock) (bool, error) { return true, nil },
+		})).To(BeEmpty())
+		Expect(client.Paused()).To(BeTrue())
+		Expect(client.Resume()).To(Succeed())
+
+		lock, err := client.Obtain("job", time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		held := client.HeldLocks()
+		Expect(held).To(HaveLen(1))
+		Expect(held[0].Key).To(Equal("job"))
+		Expect(held[0].RedisKey).To(Equal(lockKey + ":job"))
+
+		orphans, err := client.Sweep(redislock.SweepOptions{
+			Match:    "*",
+			Orphaned: func(redislock.SweptLock) (bool, error) { return true, nil },
+			DryRun:   true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]redislock.SweptLock{{Key: "job", RedisKey: lockKey + ":job", Token: lock.Token()}}))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should spread keys across cluster slots", func() {
+		spread := redislock.SpreadKey("locks:", lockKey)
+		Expect(spread).To(HavePrefix("locks:{"))
+		Expect(spread).To(HaveSuffix("}" + lockKey))
+		Expect(redislock.SpreadKey("locks:", lockKey)).To(Equal(spread))
+		Expect(redislock.SpreadKey("locks:", lockKey+"2")).NotTo(Equal(spread))
+
+		client := redislock.New(redisLockClient, redislock.WithSlotSpreading("locks:"))
+		lock, err := client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(lockKey))
+		Expect(lock.RedisKey()).To(Equal(spread))
+		Expect(redisClient.Exists(ctx, spread).Val()).To(Equal(int64(1)))
+		Expect(client.Keys("*")).To(Equal([]string{lockKey}))
+		Expect(lock.Release()).To(Succeed())
+
+		// hash tags in the prefix would defeat spreading
+		client = redislock.New(redisLockClient, redislock.WithKeyPrefix("{locks}:"), redislock.WithSlotSpreading("locks:"))
+		_, err = client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(HaveOccurred())
+		_, err = client.ObtainMulti([]string{lockKey}, time.Minute, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should prefix keys", func() {
+		client := redislock.New(redisLockClient, redislock.WithKeyPrefix("locks:"))
+		lock, err := client.Obtain(lockKey, time.Minute, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Key()).To(Equal(lockKey))
+		Expect(lock.RedisKey()).To(Equal("locks:" + lockKey))
+		Expect(redisClient.Exists(ctx, "locks:"+lockKey).Val()).To(Equal(int64(1)))
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(BeZero())
+
+		Expect(redisClient.Set(ctx, lockKey+"x", "value", time.Minute).Err()).To(Succeed())
+		defer redisClient.Del(ctx, lockKey+"x")
+		Expect(client.Keys("__bsm_*")).To(Equal([]string{lockKey}))
+		Expect(client.Keys("other*")).To(BeEmpty())
+
+		info, err := client.Inspect(lockKey)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Key).To(Equal(lockKey))
+		Expect(info.RedisKey).To(Equal("locks:" + lockKey))
+		Expect(info.Token).To(Equal(lock.Token()))
+		Expect(info.Metadata).To(Equal("my-data"))
+		Expect(info.TTL).To(BeNumerically("~", time.Minute, time.Second))
+
+		Expect(lock.Release()).To(Succeed())
+		_, err = client.Inspect(lockKey)
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should call hooks", func() {
+		hooks := new(recordingHooks)
+		client := redislock.New(redisLockClient, redislock.WithHooks(hooks))
+		lock, err := client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hooks.events()).To(Equal([]string{"attempt 1", "obtain 1"}))
+
+		_, err = client.Obtain(lockKey, time.Minute, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond), 1),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(hooks.events()).To(Equal([]string{"attempt 1", "contention 1 1ms", "attempt 2", "failed 2 redislock: not obtained"}))
+
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+		Expect(hooks.events()).To(Equal([]string{"refresh 1h0m0s <nil>", "release <nil>"}))
+
+		lock, err = client.Obtain(lockKey, 30*time.Millisecond, &redislock.Options{AutoRefresh: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+		Eventually(lock.Lost()).Should(BeClosed())
+		Expect(hooks.events()).To(ContainElement("lost redislock: not obtained"))
+
+		client = redislock.New(redisLockClient, redislock.WithHooks(nil))
+		lock, err = client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should obtain multiple keys", func() {
+		keys := []string{lockKey + "a", lockKey + "b", "{" + lockKey + "}c", "{" + lockKey + "}d"}
+		defer redisClient.Del(ctx, keys...)
+
+		lock, err := subject.Obtain(keys[1], time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.ObtainMulti(keys, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(1)))
+		Expect(lock.Release()).To(Succeed())
+
+		multi, err := subject.ObtainMulti(keys, time.Minute, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(multi.Locks()).To(HaveLen(4))
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(Equal(int64(4)))
+		for _, lock := range multi.Locks() {
+			Expect(lock.Token()).To(Equal(multi.Token()))
+			Expect(lock.Metadata()).To(Equal("my-data"))
+			Expect(lock.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		}
+		_, err = subject.Obtain(keys[2], time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(multi.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(multi.Locks()[0].TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(multi.Release()).To(Succeed())
+		Expect(redisClient.Exists(ctx, keys...).Val()).To(BeZero())
+		Expect(multi.Release()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should wake up multi key waiters on release", func() {
+		keys := []string{lockKey + "a", lockKey + "b"}
+		defer redisClient.Del(ctx, keys...)
+
+		client := redislock.New(redisLockClient, redislock.WithReleaseNotifications())
+		lock, err := client.Obtain(keys[1], time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		obtained := make(chan *redislock.MultiLock, 1)
+		go func() {
+			defer GinkgoRecover()
+			multi, err := client.ObtainMulti(keys, time.Hour, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(time.Minute),
+				ObtainTimeout: time.Minute,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			obtained <- multi
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.Release()).To(Succeed())
+
+		var multi *redislock.MultiLock
+		Eventually(obtained, time.Second).Should(Receive(&multi))
+		Expect(multi.Release()).To(Succeed())
+	})
+
+	It("should compute cluster hash slots", func() {
+		Expect(redislock.KeySlot("foo")).To(Equal(12182))
+		Expect(redislock.KeySlot("123456789")).To(Equal(12739))
+		Expect(redislock.KeySlot("{user1000}.following")).To(Equal(redislock.KeySlot("user1000")))
+		Expect(redislock.KeySlot("foo{}{bar}")).NotTo(Equal(redislock.KeySlot("bar")))
+
+		client := redislock.New(redisLockClient, redislock.WithKeyPrefix("app:"))
+		Expect(client.FenceKey("foo")).To(Equal("app:{app:foo}:__fence__"))
+		Expect(client.FenceKey("{foo}bar")).To(Equal("app:{foo}bar:__fence__"))
+		Expect(redislock.KeySlot(client.FenceKey("foo"))).To(Equal(redislock.KeySlot("app:foo")))
+	})
+
+	It("should hand off locks", func() {
+		lock, err := subject.Obtain(lockKey, time.Minute, &redislock.Options{Metadata: "my-data", MaxRefreshes: 1})
+		Expect(err).NotTo(HaveOccurred())
+		data, err := lock.Marshal()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(ContainSubstring(`"kind":"exclusive"`))
+
+		worker := redislock.New(redisLockClient)
+		restored, err := worker.UnmarshalLock(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Key()).To(Equal(lockKey))
+		Expect(restored.Token()).To(Equal(lock.Token()))
+		Expect(restored.Metadata()).To(Equal("my-data"))
+		Expect(restored.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(restored.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrRefreshLimit))
+		Expect(worker.HeldLocks()).To(HaveLen(1))
+		Expect(restored.Release()).To(Succeed())
+		Expect(lock.Release()).To(MatchError(redislock.ErrLockNotHeld))
+
+		_, err = worker.UnmarshalLock([]byte(`{}`))
+		Expect(err).To(HaveOccurred())
+		_, err = worker.UnmarshalLock([]byte(`{"kind":"other","key":"key","value":"value"}`))
+		Expect(err).To(HaveOccurred())
+
+		lock, err = subject.Obtain(lockKey, time.Minute, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = worker.LockFromToken(lockKey, "other")
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		restored, err = worker.LockFromToken(lockKey, lock.Token())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(restored.Metadata()).To(Equal("my-data"))
+		Expect(restored.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(restored.Release()).To(Succeed())
+		_, err = worker.LockFromToken(lockKey, lock.Token())
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+
+		// keys without an expiry are held until released
+		Expect(redisClient.Set(ctx, lockKey, "token", 0).Err()).To(Succeed())
+		restored, err = worker.LockFromToken(lockKey, "token")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(worker.HeldLocks()).To(HaveLen(1))
+		Expect(worker.HeldLocks()[0].TTL).To(BeZero())
+		Expect(restored.Release()).To(Succeed())
+		Expect(worker.HeldLocks()).To(BeEmpty())
+		Expect(worker.WaitDrained(ctx)).To(Succeed())
+	})
+
+	It("should log debug information", func() {
+		buf := new(bytes.Buffer)
+		client := redislock.New(redisLockClient, redislock.WithDebugLog(buf, redislock.DebugVerbose))
+		lock, err := client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+
+		Expect(buf.String()).To(ContainSubstring("obtain attempt key=" + lockKey + " attempt=1"))
+		Expect(buf.String()).To(ContainSubstring("obtained key=" + lockKey))
+		Expect(buf.String()).To(ContainSubstring("refresh key=" + lockKey + " ttl=1h0m0s err=<nil>"))
+		Expect(buf.String()).To(ContainSubstring("release key=" + lockKey + " err=<nil>"))
+	})
+
+	It("should fall back while the primary is unavailable", func() {
+		unavailable := redis.NewClient(&redis.Options{Network: "tcp", Addr: "127.0.0.1:1"})
+		defer unavailable.Close()
+
+		var transitions []bool
+		backend := redislock.NewFallback(goredisv9.NewRedisLockClient(unavailable), local.New(), func(degraded bool) {
+			transitions = append(transitions, degraded)
+		})
+		lock, err := redislock.New(backend).Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend.Degraded()).To(BeTrue())
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release()).To(Succeed())
+		Expect(transitions).To(Equal([]bool{true}))
+
+		backend = redislock.NewFallback(redisLockClient, local.New(), nil)
+		lock, err = redislock.New(backend).Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend.Degraded()).To(BeFalse())
+		Expect(redisClient.Exists(ctx, lockKey).Val()).To(Equal(int64(1)))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should back off from the primary while it is unavailable", func() {
+		primary := mock.New()
+		primary.SetError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+
+		backend := redislock.NewFallback(primary, local.New(), nil)
+		client := redislock.New(backend)
+		lock, err := client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(backend.Degraded()).To(BeTrue())
+		Expect(client.ServerProfile()).To(Equal(redislock.ServerProfile{}))
+
+		// the recovered primary is not tried again right away
+		primary.SetError(nil)
+		Expect(client.Keys("*")).To(Equal([]string{lockKey}))
+		Expect(lock.Release()).To(Succeed())
+		Expect(backend.Degraded()).To(BeTrue())
+
+		Eventually(func() bool {
+			_, _ = client.Keys("*")
+			return backend.Degraded()
+		}, 2*time.Second, 100*time.Millisecond).Should(BeFalse())
+	})
+
+	It("should sweep orphaned locks", func() {
+		otherKey := lockKey + ":other"
+		defer redisClient.Del(ctx, otherKey)
+
+		dead, err := subject.Obtain(lockKey, time.Hour, &redislock.Options{Metadata: "owner=dead"})
+		Expect(err).NotTo(HaveOccurred())
+		alive, err := subject.Obtain(otherKey, time.Hour, &redislock.Options{Metadata: "owner=alive"})
+		Expect(err).NotTo(HaveOccurred())
+
+		var reported []string
+		opt := redislock.SweepOptions{
+			Match:    lockKey + "*",
+			Orphaned: func(lock redislock.SweptLock) (bool, error) { return lock.Metadata == "owner=dead", nil },
+			DryRun:   true,
+			OnOrphan: func(lock redislock.SweptLock, err error) {
+				Expect(err).NotTo(HaveOccurred())
+				reported = append(reported, lock.Key)
+			},
+		}
+		orphans, err := subject.Sweep(opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]redislock.SweptLock{{Key: lockKey, RedisKey: lockKey, Token: dead.Token(), Metadata: "owner=dead"}}))
+		Expect(reported).To(Equal([]string{lockKey}))
+		Expect(dead.TTL()).To(BeNumerically(">", 0))
+
+		opt.DryRun = false
+		_, err = subject.Sweep(opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dead.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(alive.Release()).To(Succeed())
+
+		opt.Match = ""
+		_, err = subject.Sweep(opt)
+		Expect(err).To(HaveOccurred())
+		Expect(redisLockClient.Scan("")).To(BeEmpty())
+	})
+
+	It("should only sweep within the namespace", func() {
+		Expect(redisClient.Set(ctx, lockKey, "token", time.Minute).Err()).To(Succeed())
+
+		client := redislock.New(redisLockClient, redislock.WithKeyPrefix(lockKey+":"))
+		lock, err := client.Obtain("job", time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		orphans, err := client.Sweep(redislock.SweepOptions{
+			Match:    "*",
+			Orphaned: func(redislock.SweptLock) (bool, error) { return true, nil },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]redislock.SweptLock{{Key: "job", RedisKey: lockKey + ":job", Token: lock.Token()}}))
+		Expect(redisClient.Get(ctx, lockKey).Val()).To(Equal("token"))
+	})
+
+	It("should sweep namespaces with fenced locks", func() {
+		for _, client := range []*redislock.Client{
+			redislock.New(redisLockClient, redislock.WithKeyPrefix(lockKey+":")),
+			redislock.New(redisLockClient, redislock.WithKeyPrefix(lockKey+":"), redislock.WithSlotSpreading("s")),
+		} {
+			fenceKey := client.FenceKey("job")
+			Expect(fenceKey).To(HavePrefix(lockKey + ":"))
+
+			opt := &redislock.Options{Fence: true}
+			lock, err := client.Obtain("job", time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Release()).To(Succeed())
+			lock, err = client.Obtain("job", time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.FenceToken()).To(Equal(int64(2)))
+
+			orphans, err := client.Sweep(redislock.SweepOptions{
+				Match:    "*",
+				Orphaned: func(redislock.SweptLock) (bool, error) { return true, nil },
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(orphans).To(HaveLen(1))
+			Expect(client.Keys("*")).To(BeEmpty())
+			_, err = client.Inspect("job" + ":__fence__")
+			Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+
+			lock, err = client.Obtain("job", time.Minute, opt)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.FenceToken()).To(Equal(int64(3)))
+			Expect(lock.Release()).To(Succeed())
+			Expect(redisClient.Del(ctx, fenceKey).Err()).To(Succeed())
+		}
+	})
+
+	It("should skip other kinds of locks when sweeping", func() {
+		readKey := lockKey + ":read"
+		defer redisClient.Del(ctx, readKey)
+
+		_, err := subject.Sweep(redislock.SweepOptions{Match: lockKey + "*"})
+		Expect(err).To(HaveOccurred())
+
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		read, err := subject.ObtainRead(readKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		orphans, err := subject.Sweep(redislock.SweepOptions{
+			Match:    lockKey + "*",
+			Orphaned: func(redislock.SweptLock) (bool, error) { return true, nil },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(orphans).To(Equal([]redislock.SweptLock{{Key: lockKey, RedisKey: lockKey, Token: lock.Token()}}))
+		Expect(read.Release()).To(Succeed())
+	})
+
+	It("should drain", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		subject.Drain()
+		Expect(subject.Draining()).To(BeTrue())
+		_, err = subject.Obtain(lockKey+":other", time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrDraining))
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		Expect(subject.WaitDrained(ctx)).To(MatchError(context.DeadlineExceeded))
+
+		time.AfterFunc(10*time.Millisecond, func() { _ = lock.Release() })
+		Expect(subject.WaitDrained(context.Background())).To(Succeed())
+		Expect(subject.HeldLocks()).To(BeEmpty())
+	})
+
+	It("should detect the server profile", func() {
+		profile, err := subject.ServerProfile()
+		if err == nil && profile.Version != "" {
+			Expect(profile.String()).To(HavePrefix("redis " + profile.Version))
+		}
+
+		client := redislock.New(&inspectedClient{RedisLockClient: redisLockClient, info: "# Server\r\nredis_version:6.2.7\r\n", events: "Kx"}, redislock.WithServerDetection())
+		Expect(client.ServerProfile()).To(Equal(redislock.ServerProfile{
+			Version:               "6.2.7",
+			KeyspaceNotifications: true,
+		}))
+
+		inspected := &inspectedClient{RedisLockClient: redisLockClient, err: errors.New("unavailable")}
+		client = redislock.New(inspected)
+		_, err = client.ServerProfile()
+		Expect(err).To(MatchError("unavailable"))
+		inspected.setInfo("redis_version:7.2.0\r\n", nil)
+		Eventually(client.ServerProfile, 2*time.Second, 100*time.Millisecond).Should(Equal(redislock.ServerProfile{Version: "7.2.0"}))
+
+		client = redislock.New(&inspectedClient{RedisLockClient: redisLockClient, info: "redis_version:2.6.0\r\n"})
+		_, err = client.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrUnsupportedServer))
+
+		for _, version := range []string{"7.0", "7.2.4-vendor", "2.6.12"} {
+			client = redislock.New(&inspectedClient{RedisLockClient: redisLockClient, info: "redis_version:" + version + "\r\n"})
+			lock, err := client.Obtain(lockKey, time.Minute, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lock.Release()).To(Succeed())
+		}
+	})
+
+	It("should fail fast while paused", func() {
+		pauseKey := lockKey + ":paused"
+		defer redisClient.Del(ctx, pauseKey)
+
+		client := redislock.New(redisLockClient, redislock.WithPauseKey(pauseKey))
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(client.Paused()).To(BeFalse())
+
+		Expect(client.Pause(0)).To(Succeed())
+		Expect(client.Paused()).To(BeTrue())
+		_, err = client.Obtain(lockKey+":other", time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(time.Millisecond),
+		})
+		Expect(err).To(MatchError(redislock.ErrLockingPaused))
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+
+		Expect(client.Resume()).To(Succeed())
+		lock, err = client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Release()).To(Succeed())
+
+		Expect(client.Pause(10 * time.Millisecond)).To(Succeed())
+		Eventually(client.Paused).Should(BeFalse())
+	})
+
+	It("should retry if enabled", func() {
+		// retry, succeed
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 20*time.Millisecond).Err()).NotTo(HaveOccurred())
+
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(100*time.Millisecond), 3),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+
+		// no retry, fail
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 50*time.Millisecond).Err()).NotTo(HaveOccurred())
+
+		_, err = redislock.Obtain(redisLockClient, lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		// retry 2x, give up & fail
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 50*time.Millisecond).Err()).NotTo(HaveOccurred())
+
+		_, err = redislock.Obtain(redisLockClient, lockKey, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitRetry(redislock.LinearBackoff(time.Millisecond), 2),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should stop retrying after the obtain timeout", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+
+		start := time.Now()
+		_, err := subject.Obtain(lockKey, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+			ObtainTimeout: 50 * time.Millisecond,
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(time.Since(start)).To(BeNumerically("~", 50*time.Millisecond, 30*time.Millisecond))
+
+		Expect(redisClient.PExpire(ctx, lockKey, 20*time.Millisecond).Err()).NotTo(HaveOccurred())
+		lock, err := subject.Obtain(lockKey, 10*time.Millisecond, &redislock.Options{
+			RetryStrategy: redislock.LinearBackoff(10 * time.Millisecond),
+			ObtainTimeout: time.Second,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should block until obtained or cancelled", func() {
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).NotTo(HaveOccurred())
+		Expect(redisClient.PExpire(ctx, lockKey, 50*time.Millisecond).Err()).NotTo(HaveOccurred())
+
+		lock, err := subject.ObtainBlocking(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		_, err = subject.ObtainBlocking(lockKey, time.Hour, &redislock.Options{Context: ctx})
+		Expect(err).To(MatchError(context.DeadlineExceeded))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should prevent multiple locks (fuzzing)", func() {
+		numLocks := int32(0)
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 1000; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				wait := rand.Int63n(int64(50 * time.Millisecond))
+				time.Sleep(time.Duration(wait))
+
+				_, err := subject.Obtain(lockKey, time.Minute, nil)
+				if err == redislock.ErrNotObtained {
+					return
+				}
+				Expect(err).NotTo(HaveOccurred())
+				atomic.AddInt32(&numLocks, 1)
+			}()
+		}
+		wg.Wait()
+		Expect(numLocks).To(Equal(int32(1)))
+	})
+
+})
+
+var _ = Describe("locktest", func() {
+	AfterEach(func() {
+		Expect(redisClient.Del(ctx, lockKey).Err()).To(Succeed())
+	})
+
+	newClient := func(_ int) *redislock.Client {
+		return redislock.New(redisLockClient)
+	}
+
+	It("should implement the backend contract", func() {
+		Expect(locktest.CheckBackend(redisLockClient, lockKey)).To(Succeed())
+	})
+
+	It("should preserve mutual exclusion", func() {
+		res, err := locktest.Run(locktest.Config{
+			NewClient:     newClient,
+			Key:           lockKey,
+			Duration:      200 * time.Millisecond,
+			TTL:           time.Second,
+			RetryStrategy: func() redislock.RetryStrategy { return redislock.LinearBackoff(time.Millisecond) },
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Err()).NotTo(HaveOccurred())
+		Expect(res.Grants).To(BeNumerically(">", 1))
+		Expect(res.Overlaps).To(BeZero())
+		Expect(res.StaleWrites).To(BeZero())
+	})
+
+	It("should order fence tokens", func() {
+		defer redisClient.Del(ctx, newClient(0).FenceKey(lockKey))
+
+		res, err := locktest.Run(locktest.Config{
+			NewClient:     newClient,
+			Key:           lockKey,
+			Duration:      200 * time.Millisecond,
+			TTL:           time.Second,
+			Hold:          5 * time.Millisecond,
+			RetryStrategy: func() redislock.RetryStrategy { return redislock.LinearBackoff(time.Millisecond) },
+			Fenced:        true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Err()).NotTo(HaveOccurred())
+		Expect(res.Grants).To(BeNumerically(">", 1))
+		Expect(res.FenceRegressions).To(BeZero())
+	})
+
+	It("should tolerate injected expiries", func() {
+		res, err := locktest.Run(locktest.Config{
+			NewClient:      newClient,
+			Key:            lockKey,
+			Duration:       200 * time.Millisecond,
+			TTL:            time.Second,
+			Hold:           5 * time.Millisecond,
+			RetryStrategy:  func() redislock.RetryStrategy { return redislock.LinearBackoff(time.Millisecond) },
+			Inject:         func(key string) error { return redisClient.Del(ctx, key).Err() },
+			InjectInterval: 10 * time.Millisecond,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Err()).NotTo(HaveOccurred())
+		Expect(res.Injections).To(BeNumerically(">", 1))
+	})
+})
+
+var _ = Describe("RetryStrategy", func() {
+	It("should support no-retry", func() {
+		subject := redislock.NoRetry()
+		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
+	})
+
+	It("should support linear backoff", func() {
+		subject := redislock.LinearBackoff(time.Second)
+		Expect(subject.NextBackoff()).To(Equal(time.Second))
+		Expect(subject.NextBackoff()).To(Equal(time.Second))
+	})
+
+	It("should support limits", func() {
+		subject := redislock.LimitRetry(redislock.LinearBackoff(time.Second), 2)
+		Expect(subject.NextBackoff()).To(Equal(time.Second))
+		Expect(subject.NextBackoff()).To(Equal(time.Second))
+		Expect(subject.NextBackoff()).To(Equal(time.Duration(0)))
+	})
+
+	It("should support jitter", func() {
+		subject := redislock.ExponentialBackoffWithJitter(10*time.Millisecond, 300*time.Millisecond)
+		Expect(subject.NextBackoff()).To(BeNumerically("~", 7500*time.Microsecond, 2500*time.Microsecond))
+		Expect(subject.NextBackoff()).To(BeNumerically("~", 7500*time.Microsecond, 2500*time.Microsecond))
+		Expect(subject.NextBackoff()).To(BeNumerically("~", 12*time.Millisecond, 4*time.Millisecond))
+
+		subject = redislock.FullJitter(redislock.LinearBackoff(time.Second))
+		for i := 0; i < 100; i++ {
+			Expect(subject.NextBackoff()).To(BeNumerically("~", 500*time.Millisecond, 500*time.Millisecond))
+		}
+		Expect(redislock.FullJitter(redislock.NoRetry()).NextBackoff()).To(Equal(time.Duration(0)))
+
+		subject = redislock.DecorrelatedJitter(10*time.Millisecond, 100*time.Millisecond)
+		for i := 0; i < 100; i++ {
+			Expect(subject.NextBackoff()).To(BeNumerically("~", 55*time.Millisecond, 45*time.Millisecond))
+		}
+	})
+
+	It("should support attempt-aware strategies", func() {
+		var attempts []redislock.RetryAttempt
+		subject := redislock.RetryFunc(func(a redislock.RetryAttempt) time.Duration {
+			attempts = append(attempts, a)
+			if a.Attempt >= 3 {
+				return 0
+			}
+			return 10 * time.Millisecond
+		})
+
+		Expect(redisClient.Set(ctx, lockKey, "ABCD", 0).Err()).To(Succeed())
+		defer redisClient.Del(ctx, lockKey)
+		_, err := redislock.Obtain(redisLockClient, lockKey, time.Hour, &redislock.Options{RetryStrategy: subject})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(attempts).To(HaveLen(3))
+		Expect(attempts[2].Attempt).To(Equal(3))
+		Expect(attempts[2].Elapsed).To(BeNumerically(">=", 20*time.Millisecond))
+
+		start := time.Now()
+		_, err = redislock.Obtain(redisLockClient, lockKey, time.Hour, &redislock.Options{
+			RetryStrategy: redislock.LimitElapsed(redislock.LinearBackoff(10*time.Millisecond), 50*time.Millisecond),
+		})
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(time.Since(start)).To(BeNumerically("~", 50*time.Millisecond, 20*time.Millisecond))
+	})
+
+	It("should support exponential backoff", func() {
+		subject := redislock.ExponentialBackoff(10*time.Millisecond, 300*time.Millisecond)
+		Expect(subject.NextBackoff()).To(Equal(10 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(10 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(16 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(32 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(64 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(128 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(256 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(300 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(300 * time.Millisecond))
+		Expect(subject.NextBackoff()).To(Equal(300 * time.Millisecond))
+	})
+})
+
+// --------------------------------------------------------------------
+
+type inspectedClient struct {
+	*goredisv9.RedisLockClient
+
+	mu           sync.Mutex
+	info, events string
+	err          error
+}
+
+func (c *inspectedClient) setInfo(info string, err error) {
+	c.mu.Lock()
+	c.info, c.err = info, err
+	c.mu.Unlock()
+}
+
+func (c *inspectedClient) Info(_ string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.info, c.err
+}
+
+func (c *inspectedClient) ConfigGet(_ string) (string, error) { return c.events, nil }
+
+type recordingHooks struct {
+	redislock.NoopHooks
+
+	mu  sync.Mutex
+	log []string
+}
+
+func (h *recordingHooks) record(format string, args ...interface{}) {
+	h.mu.Lock()
+	h.log = append(h.log, fmt.Sprintf(format, args...))
+	h.mu.Unlock()
+}
+
+// events returns and resets the recorded events
+func (h *recordingHooks) events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	log := h.log
+	h.log = nil
+	return log
+}
+
+func (h *recordingHooks) OnObtainAttempt(key string, attempt int) {
+	h.record("attempt %d", attempt)
+}
+
+func (h *recordingHooks) OnContention(key string, attempt int, backoff time.Duration) {
+	h.record("contention %d %s", attempt, backoff)
+}
+
+func (h *recordingHooks) OnObtain(key string, attempts int, _ time.Duration) {
+	h.record("obtain %d", attempts)
+}
+
+func (h *recordingHooks) OnObtainFailed(key string, attempts int, _ time.Duration, err error) {
+	h.record("failed %d %v", attempts, err)
+}
+
+func (h *recordingHooks) OnRefresh(key string, ttl time.Duration, err error) {
+	h.record("refresh %s %v", ttl, err)
+}
+
+func (h *recordingHooks) OnRelease(key string, _ time.Duration, err error) {
+	h.record("release %v", err)
+}
+
+func (h *recordingHooks) OnLost(key string, err error) {
+	h.record("lost %v", err)
+}
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "redislock")
+}
+
+var ctx = context.Background()
+var redisClient *redis.Client
+var redisLockClient *goredisv9.RedisLockClient
+
+var _ = BeforeSuite(func() {
+	redisClient = redis.NewClient(&redis.Options{
+		Network: "tcp",
+		Addr:    "127.0.0.1:6379", DB: 9,
+	})
+	Expect(redisClient.Ping(ctx).Err()).To(Succeed())
+	redisLockClient = goredisv9.NewRedisLockClient(redisClient)
+})
+
+var _ = AfterSuite(func() {
+	Expect(redisClient.Close()).To(Succeed())
+})
@@ -0,0 +1,254 @@
+// Package redigo implements the redislock.Backend interface and its optional
+// interfaces for github.com/gomodule/redigo.
+//
+//	locker := redislock.New(redigo.NewRedisLockClient(pool))
+package redigo
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/gomodule/redigo/redis"
+)
+
+type RedisLockClient struct {
+	pool       *redis.Pool
+	luaRefresh *redis.Script
+	luaPttl    *redis.Script
+	luaRelease *redis.Script
+	scripts    sync.Map
+}
+
+func NewRedisLockClient(pool *redis.Pool) *RedisLockClient {
+	return &RedisLockClient{
+		pool:       pool,
+		luaRefresh: redis.NewScript(1, redislock.LuaRefreshScript),
+		luaPttl:    redis.NewScript(1, redislock.LuaPTTLScript),
+		luaRelease: redis.NewScript(1, redislock.LuaReleaseScript),
+	}
+}
+
+func (r *RedisLockClient) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	con := r.pool.Get()
+	defer con.Close()
+	_, err := redis.String(con.Do("SET", key, value, "PX", ttl.Milliseconds(), "NX"))
+	//Redigo returns nil so that means lock is not obtained so mask and return error
+	if err == redis.ErrNil {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *RedisLockClient) Refresh(key, value string, ttl string) error {
+	con := r.pool.Get()
+	defer con.Close()
+
+	status, err := redis.Int64(r.luaRefresh.Do(con, key, value, ttl))
+	if err != nil {
+		return err
+	} else if status == 1 {
+		return nil
+	}
+	//either the value did not match or key does not exist
+	return redislock.ErrNotObtained
+}
+
+func (r *RedisLockClient) Release(key, value string) error {
+	con := r.pool.Get()
+	defer con.Close()
+
+	res, err := redis.Int64(r.luaRelease.Do(con, key, value))
+	if err == redis.ErrNil {
+		return redislock.ErrLockNotHeld
+	} else if err != nil {
+		return err
+	}
+
+	if res != 1 {
+		return redislock.ErrLockNotHeld
+	}
+	return nil
+}
+
+func (r *RedisLockClient) TTL(key, value string) (int64, error) {
+	con := r.pool.Get()
+	defer con.Close()
+
+	res, err := redis.Int64(r.luaPttl.Do(con, key, value))
+	if err == redis.ErrNil {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	} else if res == -3 {
+		return 0, redislock.ErrLockNotHeld
+	}
+	return res, nil
+}
+
+func (r *RedisLockClient) Scan(match string) ([]string, error) {
+	if match == "" {
+		return nil, nil
+	}
+
+	con := r.pool.Get()
+	defer con.Close()
+
+	var keys []string
+	cursor := int64(0)
+	for {
+		res, err := redis.Values(con.Do("SCAN", cursor, "MATCH", match, "COUNT", 100))
+		if err != nil {
+			return nil, err
+		}
+
+		var page []string
+		if _, err := redis.Scan(res, &cursor, &page); err != nil {
+			return nil, err
+		}
+		keys = append(keys, page...)
+
+		if cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+func (r *RedisLockClient) Get(key string) (string, error) {
+	con := r.pool.Get()
+	defer con.Close()
+
+	res, err := redis.String(con.Do("GET", key))
+	//key does not exist
+	if err == redis.ErrNil {
+		return "", nil
+	} else if e, ok := err.(redis.Error); ok && strings.HasPrefix(string(e), "WRONGTYPE") {
+		//other kinds of locks are not stored as string
+		return "", nil
+	}
+	return res, err
+}
+
+func (r *RedisLockClient) Info(section string) (string, error) {
+	con := r.pool.Get()
+	defer con.Close()
+
+	return redis.String(con.Do("INFO", section))
+}
+
+func (r *RedisLockClient) ConfigGet(parameter string) (string, error) {
+	con := r.pool.Get()
+	defer con.Close()
+
+	res, err := redis.Strings(con.Do("CONFIG", "GET", parameter))
+	if err != nil {
+		return "", err
+	} else if len(res) < 2 {
+		return "", nil
+	}
+	return res[1], nil
+}
+
+func (r *RedisLockClient) RunScript(script *redislock.Script, keys []string, args ...string) (interface{}, error) {
+	con := r.pool.Get()
+	defer con.Close()
+
+	//scripts take a variable number of keys, so pass the key count with the args
+	s, ok := r.scripts.Load(script.Name)
+	if !ok {
+		s, _ = r.scripts.LoadOrStore(script.Name, redis.NewScript(-1, script.Source))
+	}
+
+	argv := make([]interface{}, 0, 1+len(keys)+len(args))
+	argv = append(argv, len(keys))
+	for _, key := range keys {
+		argv = append(argv, key)
+	}
+	for _, arg := range args {
+		argv = append(argv, arg)
+	}
+
+	res, err := s.(*redis.Script).Do(con, argv...)
+	if err != nil {
+		return nil, err
+	}
+	return convertReply(res), nil
+}
+
+// convertReply converts bulk replies to strings as required by redislock.Scripter
+func convertReply(res interface{}) interface{} {
+	switch v := res.(type) {
+	case []byte:
+		return string(v)
+	case []interface{}:
+		for i := range v {
+			v[i] = convertReply(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// PSubscribe subscribes to the patterns on a dedicated connection of the pool.
+func (r *RedisLockClient) PSubscribe(patterns ...string) (<-chan struct{}, func() error, error) {
+	psc := redis.PubSubConn{Conn: r.pool.Get()}
+
+	args := make([]interface{}, len(patterns))
+	for i, pattern := range patterns {
+		args[i] = pattern
+	}
+	if err := psc.PSubscribe(args...); err != nil {
+		_ = psc.Close()
+		return nil, nil, err
+	}
+
+	ch := make(chan struct{}, 1)
+	subscribed := make(chan error, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		n := 0
+		for {
+			switch v := psc.Receive().(type) {
+			case redis.Subscription:
+				if v.Kind == "punsubscribe" && v.Count == 0 {
+					return
+				} else if n++; n == len(patterns) {
+					subscribed <- nil
+				}
+			case redis.Message:
+				select {
+				case ch <- struct{}{}:
+				default:
+				}
+			case error:
+				if n < len(patterns) {
+					subscribed <- v
+				}
+				return
+			}
+		}
+	}()
+
+	if err := <-subscribed; err != nil {
+		<-done
+		_ = psc.Close()
+		return nil, nil, err
+	}
+
+	//the connection may only be closed once the receiving goroutine returned
+	unsubscribe := func() error {
+		err := psc.PUnsubscribe()
+		<-done
+		if cerr := psc.Close(); err == nil {
+			err = cerr
+		}
+		return err
+	}
+	return ch, unsubscribe, nil
+}
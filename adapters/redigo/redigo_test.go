@@ -1,4 +1,4 @@
-package garyburd_test
+package redigo_test
 
 import (
 	"math/rand"
@@ -8,8 +8,9 @@ import (
 	"time"
 
 	"github.com/dineshgowda24/redislock"
-	garyburd "github.com/dineshgowda24/redislock/examples/garyburd/redisclient"
-	"github.com/garyburd/redigo/redis"
+	"github.com/dineshgowda24/redislock/adapters/redigo"
+	"github.com/dineshgowda24/redislock/locktest"
+	"github.com/gomodule/redigo/redis"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -31,6 +32,54 @@ var _ = Describe("Client", func() {
 		Expect(err).To(Succeed())
 	})
 
+	It("should implement the backend contract", func() {
+		Expect(locktest.CheckBackend(redisClient, lockKey)).To(Succeed())
+	})
+
+	It("should scan and get keys", func() {
+		lock, err := redislock.Obtain(redisClient, lockKey, time.Hour, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisClient.Scan(lockKey + "*")).To(Equal([]string{lockKey}))
+		Expect(redisClient.Get(lockKey)).To(MatchJSON(`{"token":"` + lock.Token() + `","metadata":"my-data"}`))
+		Expect(lock.Release()).To(Succeed())
+		Expect(redisClient.Get(lockKey)).To(BeEmpty())
+	})
+
+	It("should run scripts", func() {
+		pauseKey := lockKey + ":paused"
+		client := redislock.New(redisClient, redislock.WithPauseKey(pauseKey))
+		Expect(client.Pause(time.Minute)).To(Succeed())
+		_, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrLockingPaused))
+		Expect(client.Resume()).To(Succeed())
+
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should wake up waiters on release", func() {
+		client := redislock.New(redisClient, redislock.WithReleaseNotifications())
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		obtained := make(chan *redislock.Lock, 1)
+		go func() {
+			defer GinkgoRecover()
+			lock, err := client.Obtain(lockKey, time.Hour, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(time.Minute),
+				ObtainTimeout: time.Minute,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			obtained <- lock
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.Release()).To(Succeed())
+		Eventually(obtained, time.Second).Should(Receive(&lock))
+		Expect(lock.Release()).To(Succeed())
+	})
+
 	It("should obtain once with TTL", func() {
 		lock1, err := subject.Obtain(lockKey, time.Hour, nil)
 		Expect(err).NotTo(HaveOccurred())
@@ -213,7 +262,7 @@ func TestSuite(t *testing.T) {
 }
 
 var redisPool *redis.Pool
-var redisClient *garyburd.RedisLockClient
+var redisClient *redigo.RedisLockClient
 var _ = BeforeSuite(func() {
 	redisPool = &redis.Pool{
 		MaxIdle:     3,
@@ -226,7 +275,7 @@ var _ = BeforeSuite(func() {
 	conn := redisPool.Get()
 	defer conn.Close()
 	Expect(conn.Err()).To(Succeed())
-	redisClient = garyburd.NewRedisLockClient(redisPool)
+	redisClient = redigo.NewRedisLockClient(redisPool)
 })
 
 var _ = AfterSuite(func() {
@@ -0,0 +1,231 @@
+// Package rueidis implements the redislock.Backend interface and its optional
+// interfaces for github.com/redis/rueidis.
+//
+//	locker := redislock.New(rueidis.NewRedisLockClient(client))
+package rueidis
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/redis/rueidis"
+)
+
+// errCrossSlot is returned for scripts with keys of different cluster slots,
+// which redis would reject with the same error.
+var errCrossSlot = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+
+type RedisLockClient struct {
+	client     rueidis.Client
+	luaRefresh *rueidis.Lua
+	luaPttl    *rueidis.Lua
+	luaRelease *rueidis.Lua
+	scripts    sync.Map
+}
+
+func NewRedisLockClient(client rueidis.Client) *RedisLockClient {
+	return &RedisLockClient{
+		client:     client,
+		luaRefresh: rueidis.NewLuaScript(redislock.LuaRefreshScript),
+		luaPttl:    rueidis.NewLuaScript(redislock.LuaPTTLScript),
+		luaRelease: rueidis.NewLuaScript(redislock.LuaReleaseScript),
+	}
+}
+
+func (r *RedisLockClient) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	cmd := r.client.B().Set().Key(key).Value(value).Nx().Px(ttl).Build()
+	err := r.client.Do(context.Background(), cmd).Error()
+	//a nil reply means the key exists and the lock was not obtained
+	if rueidis.IsRedisNil(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *RedisLockClient) Refresh(key, value string, ttl string) error {
+	status, err := r.luaRefresh.Exec(context.Background(), r.client, []string{key}, []string{value, ttl}).AsInt64()
+	if err != nil {
+		return err
+	} else if status == 1 {
+		return nil
+	}
+	return redislock.ErrNotObtained
+}
+
+func (r *RedisLockClient) Release(key, value string) error {
+	res, err := r.luaRelease.Exec(context.Background(), r.client, []string{key}, []string{value}).AsInt64()
+	if rueidis.IsRedisNil(err) {
+		return redislock.ErrLockNotHeld
+	} else if err != nil {
+		return err
+	}
+
+	if res != 1 {
+		return redislock.ErrLockNotHeld
+	}
+	return nil
+}
+
+func (r *RedisLockClient) TTL(key, value string) (int64, error) {
+	res, err := r.luaPttl.Exec(context.Background(), r.client, []string{key}, []string{value}).AsInt64()
+	if rueidis.IsRedisNil(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	} else if res == -3 {
+		return 0, redislock.ErrLockNotHeld
+	}
+	return res, nil
+}
+
+// Scan returns all keys matching the glob-style pattern, none for an empty
+// pattern. On a cluster all nodes are scanned.
+func (r *RedisLockClient) Scan(match string) ([]string, error) {
+	//without MATCH the whole keyspace would be scanned
+	if match == "" {
+		return nil, nil
+	}
+
+	ctx := context.Background()
+
+	nodes := r.client.Nodes()
+	if len(nodes) < 2 {
+		return scan(ctx, r.client, match)
+	}
+
+	//replicas return the keys of their masters, so deduplicate
+	seen := make(map[string]struct{})
+	var keys []string
+	for _, node := range nodes {
+		res, err := scan(ctx, node, match)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range res {
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys, nil
+}
+
+func scan(ctx context.Context, client rueidis.Client, match string) ([]string, error) {
+	var keys []string
+	var cursor uint64
+	for {
+		entry, err := client.Do(ctx, client.B().Scan().Cursor(cursor).Match(match).Count(100).Build()).AsScanEntry()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, entry.Elements...)
+
+		if cursor = entry.Cursor; cursor == 0 {
+			return keys, nil
+		}
+	}
+}
+
+func (r *RedisLockClient) Get(key string) (string, error) {
+	res, err := r.client.Do(context.Background(), r.client.B().Get().Key(key).Build()).ToString()
+	if rueidis.IsRedisNil(err) {
+		return "", nil
+	} else if ret, ok := rueidis.IsRedisErr(err); ok && strings.HasPrefix(ret.Error(), "WRONGTYPE") {
+		//other kinds of locks are not stored as string
+		return "", nil
+	}
+	return res, err
+}
+
+func (r *RedisLockClient) Info(section string) (string, error) {
+	return r.client.Do(context.Background(), r.client.B().Info().Section(section).Build()).ToString()
+}
+
+func (r *RedisLockClient) ConfigGet(parameter string) (string, error) {
+	res, err := r.client.Do(context.Background(), r.client.B().ConfigGet().Parameter(parameter).Build()).AsStrMap()
+	if err != nil {
+		return "", err
+	}
+	return res[parameter], nil
+}
+
+// RunScript evaluates the script by its SHA1 digest and loads it on a NOSCRIPT
+// error. On a cluster all keys must hash to the same slot.
+func (r *RedisLockClient) RunScript(script *redislock.Script, keys []string, args ...string) (interface{}, error) {
+	sha, ok := r.scripts.Load(script.Name)
+	if !ok {
+		sum := sha1.Sum([]byte(script.Source))
+		sha, _ = r.scripts.LoadOrStore(script.Name, hex.EncodeToString(sum[:]))
+	}
+
+	cmd, err := r.evalCmd("EVALSHA", sha.(string), keys, args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	res, err := r.client.Do(ctx, cmd).ToAny()
+	if ret, ok := rueidis.IsRedisErr(err); ok && ret.IsNoScript() {
+		if cmd, err = r.evalCmd("EVAL", script.Source, keys, args); err != nil {
+			return nil, err
+		}
+		res, err = r.client.Do(ctx, cmd).ToAny()
+	}
+	if rueidis.IsRedisNil(err) {
+		return nil, nil
+	}
+	return res, err
+}
+
+// evalCmd builds the command. The command builder of cluster clients panics if
+// the keys hash to different slots, which is returned as errCrossSlot instead.
+func (r *RedisLockClient) evalCmd(name, body string, keys, args []string) (cmd rueidis.Completed, err error) {
+	defer func() {
+		if recover() != nil {
+			err = errCrossSlot
+		}
+	}()
+
+	return r.client.B().Arbitrary(name, body, strconv.Itoa(len(keys))).Keys(keys...).Args(args...).Build(), nil
+}
+
+// PSubscribe subscribes to the patterns on a dedicated connection. On a
+// cluster it subscribes on a single node as messages are broadcast.
+func (r *RedisLockClient) PSubscribe(patterns ...string) (<-chan struct{}, func() error, error) {
+	var node rueidis.Client
+	for _, node = range r.client.Nodes() {
+		break
+	}
+
+	ch := make(chan struct{}, 1)
+	dc, cancel := node.Dedicate()
+	dc.SetPubSubHooks(rueidis.PubSubHooks{
+		OnMessage: func(_ rueidis.PubSubMessage) {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		},
+	})
+
+	unsubscribe := func() error {
+		dc.Close()
+		cancel()
+		return nil
+	}
+	if err := dc.Do(context.Background(), dc.B().Psubscribe().Pattern(patterns...).Build()).Error(); err != nil {
+		_ = unsubscribe()
+		return nil, nil, err
+	}
+	return ch, unsubscribe, nil
+}
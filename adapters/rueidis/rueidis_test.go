@@ -0,0 +1,175 @@
+package rueidis_test
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	adapter "github.com/dineshgowda24/redislock/adapters/rueidis"
+	"github.com/dineshgowda24/redislock/locktest"
+	"github.com/redis/rueidis"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lockKey = "__bsm_redislock_unit_test__"
+
+var _ = Describe("Client", func() {
+	var subject *redislock.Client
+
+	BeforeEach(func() {
+		subject = redislock.New(redisLockClient)
+	})
+
+	AfterEach(func() {
+		Expect(do(redisClient.B().Del().Key(lockKey).Build())).To(Succeed())
+	})
+
+	It("should implement the backend contract", func() {
+		Expect(locktest.CheckBackend(redisLockClient, lockKey)).To(Succeed())
+	})
+
+	It("should scan and get keys", func() {
+		lock, err := redislock.Obtain(redisLockClient, lockKey, time.Hour, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(redisLockClient.Scan(lockKey + "*")).To(Equal([]string{lockKey}))
+		Expect(redisLockClient.Get(lockKey)).To(MatchJSON(`{"token":"` + lock.Token() + `","metadata":"my-data"}`))
+		Expect(lock.Release()).To(Succeed())
+		Expect(redisLockClient.Get(lockKey)).To(BeEmpty())
+	})
+
+	It("should run scripts", func() {
+		pauseKey := "{" + lockKey + "}:paused"
+		client := redislock.New(redisLockClient, redislock.WithPauseKey(pauseKey))
+		Expect(client.Pause(time.Minute)).To(Succeed())
+		_, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrLockingPaused))
+		Expect(client.Resume()).To(Succeed())
+
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should wake up waiters on release", func() {
+		client := redislock.New(redisLockClient, redislock.WithReleaseNotifications())
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		obtained := make(chan *redislock.Lock, 1)
+		go func() {
+			defer GinkgoRecover()
+			lock, err := client.Obtain(lockKey, time.Hour, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(time.Minute),
+				ObtainTimeout: time.Minute,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			obtained <- lock
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.Release()).To(Succeed())
+		Eventually(obtained, time.Second).Should(Receive(&lock))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should obtain once with TTL", func() {
+		lock1, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock1.Token()).To(HaveLen(22))
+		Expect(lock1.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		defer lock1.Release()
+
+		_, err = subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+		Expect(lock1.Release()).To(Succeed())
+
+		lock2, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock2.Release()).To(Succeed())
+	})
+
+	It("should refresh", func() {
+		lock, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should fail to release if obtained by someone else", func() {
+		lock, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(do(redisClient.B().Set().Key(lockKey).Value("ABCD").Build())).To(Succeed())
+		Expect(lock.Release()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should fail to refresh if expired", func() {
+		lock, err := subject.Obtain(lockKey, time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+		Expect(lock.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should prevent multiple locks (fuzzing)", func() {
+		numLocks := int32(0)
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 1000; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				wait := rand.Int63n(int64(50 * time.Millisecond))
+				time.Sleep(time.Duration(wait))
+
+				_, err := subject.Obtain(lockKey, time.Minute, nil)
+				if err == redislock.ErrNotObtained {
+					return
+				}
+				Expect(err).NotTo(HaveOccurred())
+				atomic.AddInt32(&numLocks, 1)
+			}()
+		}
+		wg.Wait()
+		Expect(numLocks).To(Equal(int32(1)))
+	})
+})
+
+// --------------------------------------------------------------------
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "rueidis")
+}
+
+func do(cmd rueidis.Completed) error {
+	return redisClient.Do(context.Background(), cmd).Error()
+}
+
+var redisClient rueidis.Client
+var redisLockClient *adapter.RedisLockClient
+
+var _ = BeforeSuite(func() {
+	var err error
+	redisClient, err = rueidis.NewClient(rueidis.ClientOption{
+		InitAddress:  []string{"127.0.0.1:6379"},
+		SelectDB:     2,
+		DisableCache: true,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	redisLockClient = adapter.NewRedisLockClient(redisClient)
+})
+
+var _ = AfterSuite(func() {
+	redisClient.Close()
+})
@@ -0,0 +1,109 @@
+package redislock
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+var errSpreadHashTag = errors.New("redislock: key prefixes must not contain { or } with slot spreading")
+
+// SpreadKey decorates key with a redis cluster hash tag derived from the key
+// itself, i.e. prefix{tag}key. Unrelated keys are distributed evenly across
+// all cluster slots, even if they share a common prefix, while remaining
+// discoverable by scanning for prefix*. The prefix must not contain a hash
+// tag itself.
+func SpreadKey(prefix, key string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return prefix + "{" + strconv.FormatUint(uint64(h.Sum32()), 16) + "}" + key
+}
+
+// KeySlot returns the redis cluster hash slot of key, which is derived from
+// its hash tag, if any.
+func KeySlot(key string) int {
+	if tag, ok := hashTag(key); ok {
+		key = tag
+	}
+	return int(crc16(key) % 16384)
+}
+
+// hashTag returns the hash tag of key, i.e. the part between the first { and
+// the following }, if not empty.
+func hashTag(key string) (string, bool) {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return "", false
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return "", false
+	}
+	return key[start+1 : start+1+end], true
+}
+
+// crc16 implements the CRC16-XMODEM checksum used by redis cluster.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// WithSlotSpreading decorates all lock keys of the client using SpreadKey
+// with the given prefix, inside the prefix of WithKeyPrefix. Neither prefix
+// may contain { or }, as redis would hash all keys on the first hash tag,
+// obtaining locks fails otherwise.
+func WithSlotSpreading(prefix string) ClientOption {
+	return func(c *Client) {
+		c.spreadSlots = true
+		c.spreadPrefix = prefix
+	}
+}
+
+// checkSpreading returns an error if the prefixes of the client would defeat
+// slot spreading.
+func (c *Client) checkSpreading() error {
+	if c.spreadSlots && strings.ContainsAny(c.keyPrefix+c.spreadPrefix, "{}") {
+		return errSpreadHashTag
+	}
+	return nil
+}
+
+// redisKey returns the key under which a lock is stored in redis.
+func (c *Client) redisKey(key string) string {
+	if c.spreadSlots {
+		key = SpreadKey(c.spreadPrefix, key)
+	}
+	return c.keyPrefix + key
+}
+
+// logicalKey reverses redisKey. It reports false if redisKey was not derived
+// from a key by the client.
+func (c *Client) logicalKey(redisKey string) (string, bool) {
+	if !strings.HasPrefix(redisKey, c.keyPrefix) {
+		return "", false
+	}
+	key := redisKey[len(c.keyPrefix):]
+
+	if c.spreadSlots {
+		if !strings.HasPrefix(key, c.spreadPrefix+"{") {
+			return "", false
+		}
+		end := strings.IndexByte(key, '}')
+		if end < 0 {
+			return "", false
+		}
+		key = key[end+1:]
+	}
+	return key, c.redisKey(key) == redisKey
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/dineshgowda24/redislock/adapters/goredisv9"
+	"github.com/redis/go-redis/v9"
+)
+
+type benchConfig struct {
+	addr     string
+	db       int
+	prefix   string
+	workers  int
+	keys     int
+	ttl      time.Duration
+	hold     time.Duration
+	duration time.Duration
+	retry    string
+	retries  int
+}
+
+func bench(args []string) error {
+	var cfg benchConfig
+
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.StringVar(&cfg.addr, "addr", "127.0.0.1:6379", "redis address")
+	fs.IntVar(&cfg.db, "db", 0, "redis database")
+	fs.StringVar(&cfg.prefix, "prefix", "redislock:bench:", "key prefix")
+	fs.IntVar(&cfg.workers, "workers", 50, "number of contending workers")
+	fs.IntVar(&cfg.keys, "keys", 10, "number of distinct lock keys")
+	fs.DurationVar(&cfg.ttl, "ttl", time.Second, "lock TTL")
+	fs.DurationVar(&cfg.hold, "hold", 5*time.Millisecond, "time a lock is held before release")
+	fs.DurationVar(&cfg.duration, "duration", 10*time.Second, "benchmark duration")
+	fs.StringVar(&cfg.retry, "retry", "none", "retry strategy: none, linear:<backoff> or exp:<min>:<max>")
+	fs.IntVar(&cfg.retries, "retries", 0, "maximum retries per obtain, 0 for unlimited")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if cfg.workers < 1 || cfg.keys < 1 {
+		return errors.New("workers and keys must be positive")
+	}
+	if _, err := parseRetry(cfg.retry, cfg.retries); err != nil {
+		return err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Network:  "tcp",
+		Addr:     cfg.addr,
+		DB:       cfg.db,
+		PoolSize: cfg.workers,
+	})
+	defer client.Close()
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		return err
+	}
+
+	before, _ := commandsProcessed(client)
+	res := runBench(cfg, redislock.New(goredisv9.NewRedisLockClient(client)))
+	after, _ := commandsProcessed(client)
+
+	res.print(os.Stdout, cfg, before, after)
+	return nil
+}
+
+// parseRetry parses a retry strategy flag. It must be called for every
+// obtain as strategies are stateful.
+func parseRetry(s string, retries int) (redislock.RetryStrategy, error) {
+	parts := strings.Split(s, ":")
+
+	var durations []time.Duration
+	for _, p := range parts[1:] {
+		d, err := time.ParseDuration(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry strategy %q: %v", s, err)
+		}
+		durations = append(durations, d)
+	}
+
+	var strategy redislock.RetryStrategy
+	switch {
+	case parts[0] == "none" && len(durations) == 0:
+		return redislock.NoRetry(), nil
+	case parts[0] == "linear" && len(durations) == 1:
+		strategy = redislock.LinearBackoff(durations[0])
+	case parts[0] == "exp" && len(durations) == 2:
+		strategy = redislock.ExponentialBackoff(durations[0], durations[1])
+	default:
+		return nil, fmt.Errorf("invalid retry strategy %q", s)
+	}
+
+	if retries > 0 {
+		strategy = redislock.LimitRetry(strategy, retries)
+	}
+	return strategy, nil
+}
+
+// commandsProcessed returns the total number of commands processed by redis.
+func commandsProcessed(client *redis.Client) (int64, error) {
+	info, err := client.Info(context.Background(), "stats").Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if v := strings.TrimPrefix(line, "total_commands_processed:"); v != line {
+			return strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		}
+	}
+	return 0, errors.New("total_commands_processed not reported")
+}
+
+type benchResult struct {
+	elapsed   time.Duration
+	latencies []time.Duration
+	contended int
+	errors    int
+}
+
+func runBench(cfg benchConfig, locker *redislock.Client) *benchResult {
+	res := new(benchResult)
+	mu := new(sync.Mutex)
+	wg := new(sync.WaitGroup)
+
+	start := time.Now()
+	deadline := start.Add(cfg.duration)
+	for i := 0; i < cfg.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for time.Now().Before(deadline) {
+				key := cfg.prefix + strconv.Itoa(rand.Intn(cfg.keys))
+				retry, _ := parseRetry(cfg.retry, cfg.retries)
+
+				obtainStart := time.Now()
+				lock, err := locker.Obtain(key, cfg.ttl, &redislock.Options{RetryStrategy: retry})
+				latency := time.Since(obtainStart)
+
+				mu.Lock()
+				switch err {
+				case nil:
+					res.latencies = append(res.latencies, latency)
+				case redislock.ErrNotObtained:
+					res.contended++
+				default:
+					res.errors++
+				}
+				mu.Unlock()
+
+				if err == nil {
+					time.Sleep(cfg.hold)
+					_ = lock.Release()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	res.elapsed = time.Since(start)
+	return res
+}
+
+func (r *benchResult) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	return r.latencies[int(float64(len(r.latencies)-1)*p)]
+}
+
+func (r *benchResult) print(out io.Writer, cfg benchConfig, before, after int64) {
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	secs := r.elapsed.Seconds()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "workers\t%d\n", cfg.workers)
+	fmt.Fprintf(w, "keys\t%d\n", cfg.keys)
+	fmt.Fprintf(w, "retry\t%s\n", cfg.retry)
+	fmt.Fprintf(w, "elapsed\t%s\n", r.elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "obtained\t%d (%.1f/s)\n", len(r.latencies), float64(len(r.latencies))/secs)
+	fmt.Fprintf(w, "not obtained\t%d\n", r.contended)
+	fmt.Fprintf(w, "errors\t%d\n", r.errors)
+	for _, p := range []float64{0.5, 0.9, 0.99} {
+		fmt.Fprintf(w, "latency p%g\t%s\n", p*100, r.percentile(p))
+	}
+	fmt.Fprintf(w, "latency max\t%s\n", r.percentile(1))
+
+	if after > before {
+		fmt.Fprintf(w, "redis commands\t%d (%.1f/s)\n", after-before, float64(after-before)/secs)
+	} else {
+		fmt.Fprintf(w, "redis commands\tn/a\n")
+	}
+}
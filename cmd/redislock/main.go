@@ -0,0 +1,43 @@
+// Command redislock provides tooling for redislock deployments.
+//
+// Usage:
+//
+//	redislock bench [flags]
+//
+// The bench command runs contending workers against a redis server and
+// reports obtain latency percentiles and the load caused on redis. Run
+// redislock bench -h for the available flags.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch cmd := os.Args[1]; cmd {
+	case "bench":
+		if err := bench(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "redislock:", err)
+			os.Exit(1)
+		}
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "redislock: unknown command %q\n", cmd)
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: redislock <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  bench    benchmark obtain latency and redis load")
+}
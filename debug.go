@@ -0,0 +1,38 @@
+package redislock
+
+import (
+	"io"
+	"log"
+)
+
+// DebugLevel controls the verbosity of the debug log.
+type DebugLevel int
+
+const (
+	// DebugOff disables debug logging.
+	DebugOff DebugLevel = iota
+
+	// DebugInfo logs the outcome of every obtain, refresh and release.
+	DebugInfo
+
+	// DebugVerbose additionally logs every obtain attempt and the chosen
+	// backoff between attempts.
+	DebugVerbose
+)
+
+// WithDebugLog enables debug logging of lock operations to w at the given
+// verbosity. It is intended for diagnosing environments where custom
+// instrumentation cannot be deployed.
+func WithDebugLog(w io.Writer, level DebugLevel) ClientOption {
+	return func(c *Client) {
+		c.debugLog = log.New(w, "redislock: ", log.LstdFlags|log.Lmicroseconds)
+		c.debugLevel = level
+	}
+}
+
+func (c *Client) debugf(level DebugLevel, format string, args ...interface{}) {
+	if c.debugLog == nil || level > c.debugLevel {
+		return
+	}
+	c.debugLog.Printf(format, args...)
+}
@@ -0,0 +1,56 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Do obtains a lock using a key with the given TTL and runs fn while holding
+// it. The lock is refreshed in the background, see Options.AutoRefresh, and
+// released when fn returns. The context passed to fn is cancelled when ctx is
+// cancelled or the lock is lost. ctx also controls the obtain, the Context,
+// WatchdogContext and AutoRefresh fields of the options are ignored.
+// May return ErrNotObtained if the lock cannot be obtained, ErrLockLost if it
+// was lost while fn was running, or the error returned by fn. If the lock was
+// lost and fn failed, the error returned by fn is joined to ErrLockLost, use
+// errors.Is to check for either.
+func (c *Client) Do(ctx context.Context, key string, ttl time.Duration, opt *Options, fn func(ctx context.Context) error) error {
+	var o Options
+	if opt != nil {
+		o = *opt
+	}
+	o.Context = ctx
+	o.WatchdogContext = nil
+	o.AutoRefresh = true
+
+	lock, err := c.Obtain(key, ttl, &o)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err = fn(ctx)
+	releaseErr := lock.Release()
+
+	// the lock may expire before the watchdog notices
+	if lock.Err() != nil || releaseErr == ErrLockNotHeld {
+		if err != nil {
+			return errors.Join(ErrLockLost, err)
+		}
+		return ErrLockLost
+	} else if err != nil {
+		return err
+	}
+	return releaseErr
+}
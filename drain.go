@@ -0,0 +1,62 @@
+package redislock
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Drain puts the client into drain mode. New obtains fail with ErrDraining
+// while existing locks can still be refreshed and released. Use WaitDrained
+// to wait until all locks held by the client are gone, e.g. in a pre-stop
+// hook.
+func (c *Client) Drain() {
+	atomic.StoreInt32(&c.draining, 1)
+}
+
+// Draining reports whether the client is in drain mode.
+func (c *Client) Draining() bool {
+	return atomic.LoadInt32(&c.draining) == 1
+}
+
+// WaitDrained blocks until the client holds no more locks, either because
+// they were released or have expired, or until ctx is cancelled.
+func (c *Client) WaitDrained(ctx context.Context) error {
+	for {
+		c.heldMu.Lock()
+		changed := c.heldChanged
+		c.heldMu.Unlock()
+
+		held := c.HeldLocks()
+		if len(held) == 0 {
+			return nil
+		}
+
+		// wake up on release or when the next lock expires, locks without an
+		// expiry are only released
+		var next time.Duration
+		for _, h := range held {
+			if h.TTL > 0 && (next == 0 || h.TTL < next) {
+				next = h.TTL
+			}
+		}
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if next > 0 {
+			timer = time.NewTimer(next)
+			expired = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
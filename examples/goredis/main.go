@@ -6,8 +6,8 @@ import (
 	"time"
 
 	"github.com/dineshgowda24/redislock"
-	goredis "github.com/dineshgowda24/redislock/examples/goredis/redisclient"
-	"github.com/go-redis/redis/v7"
+	"github.com/dineshgowda24/redislock/adapters/goredisv9"
+	"github.com/redis/go-redis/v9"
 )
 
 func main() {
@@ -18,7 +18,7 @@ func main() {
 	})
 
 	// Create a new lock client.
-	locker := redislock.New(goredis.NewRedisLockClient(redisClient))
+	locker := redislock.New(goredisv9.NewRedisLockClient(redisClient))
 
 	// Try to obtain lock.
 	lock, err := locker.Obtain("my-key", 100*time.Millisecond, nil)
@@ -47,10 +47,10 @@ func main() {
 
 	// Sleep a little longer, then check.
 	time.Sleep(100 * time.Millisecond)
-	if ttl, err := lock.TTL(); err != nil {
-		log.Fatalln(err)
-	} else if ttl == 0 {
+	if _, err := lock.TTL(); err == redislock.ErrLockNotHeld {
 		fmt.Println("Now, my lock has expired!")
+	} else if err != nil {
+		log.Fatalln(err)
 	}
 
 	// Output:
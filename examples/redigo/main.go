@@ -6,8 +6,8 @@ import (
 	"time"
 
 	"github.com/dineshgowda24/redislock"
-	garyburd "github.com/dineshgowda24/redislock/examples/garyburd/redisclient"
-	"github.com/garyburd/redigo/redis"
+	"github.com/dineshgowda24/redislock/adapters/redigo"
+	"github.com/gomodule/redigo/redis"
 )
 
 func main() {
@@ -22,7 +22,7 @@ func main() {
 	}
 
 	// Create a new lock client.
-	locker := redislock.New(garyburd.NewRedisLockClient(pool))
+	locker := redislock.New(redigo.NewRedisLockClient(pool))
 
 	// Try to obtain lock.
 	lock, err := locker.Obtain("my-key", 100*time.Millisecond, nil)
@@ -51,10 +51,10 @@ func main() {
 
 	// Sleep a little longer, then check.
 	time.Sleep(100 * time.Millisecond)
-	if ttl, err := lock.TTL(); err != nil {
-		log.Fatalln(err)
-	} else if ttl == 0 {
+	if _, err := lock.TTL(); err == redislock.ErrLockNotHeld {
 		fmt.Println("Now, my lock has expired!")
+	} else if err != nil {
+		log.Fatalln(err)
 	}
 
 	// Output:
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	adapter "github.com/dineshgowda24/redislock/adapters/rueidis"
+	"github.com/redis/rueidis"
+)
+
+func main() {
+	// Connect to redis.
+	client, err := rueidis.NewClient(rueidis.ClientOption{
+		InitAddress:  []string{"127.0.0.1:6379"},
+		SelectDB:     2,
+		DisableCache: true,
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer client.Close()
+
+	// Create a new lock client.
+	locker := redislock.New(adapter.NewRedisLockClient(client))
+
+	// Try to obtain lock.
+	lock, err := locker.Obtain("my-key", 100*time.Millisecond, nil)
+	if err == redislock.ErrNotObtained {
+		fmt.Println("Could not obtain lock!")
+	} else if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Don't forget to defer Release.
+	defer lock.Release()
+	fmt.Println("I have a lock!")
+
+	// Sleep and check the remaining TTL.
+	time.Sleep(50 * time.Millisecond)
+	if ttl, err := lock.TTL(); err != nil {
+		log.Fatalln(err)
+	} else if ttl > 0 {
+		fmt.Println("Yay, I still have my lock!")
+	}
+
+	// Extend my lock.
+	if err := lock.Refresh(100*time.Millisecond, nil); err != nil {
+		log.Fatalln(err)
+	}
+
+	// Sleep a little longer, then check.
+	time.Sleep(100 * time.Millisecond)
+	if _, err := lock.TTL(); err == redislock.ErrLockNotHeld {
+		fmt.Println("Now, my lock has expired!")
+	} else if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Output:
+	// I have a lock!
+	// Yay, I still have my lock!
+	// Now, my lock has expired!
+}
@@ -0,0 +1,250 @@
+package redislock
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// fallbackRetryInterval is the time a FallbackClient uses the fallback only
+// after the primary failed, before trying the primary again.
+const fallbackRetryInterval = time.Second
+
+// FallbackClient is a Backend which uses a primary client and falls back
+// to a secondary client, e.g. another redis or a process-local backend, while
+// the primary fails with errors other than ErrNotObtained or ErrLockNotHeld.
+// Once the primary failed, it is only tried again after a second, so that
+// operations do not wait for its timeouts during an outage.
+//
+// The optional interfaces Scripter, Scanner, Subscriber and ServerInspector
+// are forwarded to the backend in use and return ErrNotSupported if it does
+// not implement them.
+//
+// While degraded, locks are only exclusive among the users of the fallback.
+// A lock obtained on the fallback is not visible to the primary, so once the
+// primary recovers the same key may be obtained twice. Only use it for locks
+// which prefer weaker guarantees over unavailability.
+type FallbackClient struct {
+	primary  Backend
+	fallback Backend
+	notify   func(degraded bool)
+	degraded int32
+	retryAt  int64 // unix nanoseconds
+}
+
+// NewFallback creates a new FallbackClient. The optional notify func is called
+// whenever the client enters or leaves degraded mode.
+func NewFallback(primary, fallback Backend, notify func(degraded bool)) *FallbackClient {
+	return &FallbackClient{primary: primary, fallback: fallback, notify: notify}
+}
+
+// Degraded reports whether the fallback is in use because the last operation
+// on the primary failed.
+func (c *FallbackClient) Degraded() bool {
+	return atomic.LoadInt32(&c.degraded) == 1
+}
+
+func (c *FallbackClient) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	if !c.tryPrimary() {
+		return c.fallback.SetNX(key, value, ttl)
+	}
+
+	ok, err := c.primary.SetNX(key, value, ttl)
+	if !c.healthy(err) {
+		return c.fallback.SetNX(key, value, ttl)
+	}
+	return ok, nil
+}
+
+func (c *FallbackClient) Refresh(key, value string, ttl string) error {
+	if !c.tryPrimary() {
+		return c.fallback.Refresh(key, value, ttl)
+	}
+
+	err := c.primary.Refresh(key, value, ttl)
+	if !c.healthy(err) {
+		return c.fallback.Refresh(key, value, ttl)
+	}
+
+	// the lock may have been obtained on the fallback
+	if err == ErrNotObtained && c.fallback.Refresh(key, value, ttl) == nil {
+		return nil
+	}
+	return err
+}
+
+func (c *FallbackClient) Release(key, value string) error {
+	if !c.tryPrimary() {
+		return c.fallback.Release(key, value)
+	}
+
+	err := c.primary.Release(key, value)
+	if !c.healthy(err) {
+		return c.fallback.Release(key, value)
+	}
+
+	// the lock may have been obtained on the fallback
+	if err == ErrLockNotHeld && c.fallback.Release(key, value) == nil {
+		return nil
+	}
+	return err
+}
+
+func (c *FallbackClient) TTL(key, value string) (int64, error) {
+	if !c.tryPrimary() {
+		return c.fallback.TTL(key, value)
+	}
+
+	res, err := c.primary.TTL(key, value)
+	if !c.healthy(err) {
+		return c.fallback.TTL(key, value)
+	}
+
+	// the lock may have been obtained on the fallback
+	if err == ErrLockNotHeld || res == 0 || res == -3 {
+		if fres, ferr := c.fallback.TTL(key, value); ferr == nil && fres > 0 {
+			return fres, nil
+		}
+	}
+	return res, err
+}
+
+// RunScript runs script on the primary or, while it is unavailable, on the
+// fallback. May return ErrNotSupported if either does not implement Scripter.
+func (c *FallbackClient) RunScript(script *Script, keys []string, args ...string) (interface{}, error) {
+	primary, ok := c.primary.(Scripter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	fallback, ok := c.fallback.(Scripter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if !c.tryPrimary() {
+		return fallback.RunScript(script, keys, args...)
+	}
+	res, err := primary.RunScript(script, keys, args...)
+	if !c.healthy(err) {
+		return fallback.RunScript(script, keys, args...)
+	}
+	return res, err
+}
+
+// Scan returns the keys matching the glob-style pattern on the backend in
+// use. May return ErrNotSupported if it does not implement Scanner.
+func (c *FallbackClient) Scan(match string) ([]string, error) {
+	if c.tryPrimary() {
+		res, err := backendScan(c.primary, match)
+		if c.reachable(err) {
+			return res, err
+		}
+	}
+	return backendScan(c.fallback, match)
+}
+
+// Get returns the value of key on the backend in use. May return
+// ErrNotSupported if it does not implement Scanner.
+func (c *FallbackClient) Get(key string) (string, error) {
+	if c.tryPrimary() {
+		res, err := backendGet(c.primary, key)
+		if c.reachable(err) {
+			return res, err
+		}
+	}
+	return backendGet(c.fallback, key)
+}
+
+// PSubscribe subscribes to the patterns on the backend in use. May return
+// ErrNotSupported if it does not implement Subscriber.
+func (c *FallbackClient) PSubscribe(patterns ...string) (<-chan struct{}, func() error, error) {
+	backend := c.fallback
+	if c.tryPrimary() {
+		backend = c.primary
+	}
+
+	subscriber, ok := backend.(Subscriber)
+	if !ok {
+		return nil, nil, ErrNotSupported
+	}
+	return subscriber.PSubscribe(patterns...)
+}
+
+// Info returns the output of the INFO command on the backend in use. May
+// return ErrNotSupported if it does not implement ServerInspector.
+func (c *FallbackClient) Info(section string) (string, error) {
+	if c.tryPrimary() {
+		res, err := backendInfo(c.primary, section)
+		if c.reachable(err) {
+			return res, err
+		}
+	}
+	return backendInfo(c.fallback, section)
+}
+
+// ConfigGet returns a configuration parameter of the backend in use. May
+// return ErrNotSupported if it does not implement ServerInspector.
+func (c *FallbackClient) ConfigGet(parameter string) (string, error) {
+	backend := c.fallback
+	if c.tryPrimary() {
+		backend = c.primary
+	}
+
+	inspector, ok := backend.(ServerInspector)
+	if !ok {
+		return "", ErrNotSupported
+	}
+	return inspector.ConfigGet(parameter)
+}
+
+// reachable records the outcome of an inspection of the primary and reports
+// whether its result can be used. Errors replied by the primary, e.g. an
+// unknown command, do not make it unavailable.
+func (c *FallbackClient) reachable(err error) bool {
+	if err != nil && !transient(err) {
+		return true
+	}
+	return c.healthy(err)
+}
+
+// tryPrimary reports whether the primary should be used, i.e. it is healthy
+// or was last tried at least fallbackRetryInterval ago.
+func (c *FallbackClient) tryPrimary() bool {
+	return !c.Degraded() || time.Now().UnixNano() >= atomic.LoadInt64(&c.retryAt)
+}
+
+// healthy records the outcome of an operation on the primary and reports
+// whether its result can be used.
+func (c *FallbackClient) healthy(err error) bool {
+	ok := err == nil || err == ErrNotObtained || err == ErrLockNotHeld
+
+	var degraded int32
+	if !ok {
+		degraded = 1
+		atomic.StoreInt64(&c.retryAt, time.Now().Add(fallbackRetryInterval).UnixNano())
+	}
+	if atomic.SwapInt32(&c.degraded, degraded) != degraded && c.notify != nil {
+		c.notify(!ok)
+	}
+	return ok
+}
+
+func backendScan(backend Backend, match string) ([]string, error) {
+	if scanner, ok := backend.(Scanner); ok {
+		return scanner.Scan(match)
+	}
+	return nil, ErrNotSupported
+}
+
+func backendGet(backend Backend, key string) (string, error) {
+	if scanner, ok := backend.(Scanner); ok {
+		return scanner.Get(key)
+	}
+	return "", ErrNotSupported
+}
+
+func backendInfo(backend Backend, section string) (string, error) {
+	if inspector, ok := backend.(ServerInspector); ok {
+		return inspector.Info(section)
+	}
+	return "", ErrNotSupported
+}
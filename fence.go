@@ -0,0 +1,61 @@
+package redislock
+
+import "strings"
+
+// lua script for fenced locks, run through the Scripter interface
+const LuaObtainFencedScript = `if KEYS[3] and redis.call("exists", KEYS[3]) == 1 then return -1 end if redis.call("set", KEYS[1], ARGV[1], "PX", ARGV[2], "NX") then return redis.call("incr", KEYS[2]) else return 0 end`
+
+// ObtainFencedScript sets KEYS[1] to ARGV[1] with a TTL of ARGV[2]
+// milliseconds unless it exists and increments the fence counter KEYS[2].
+// Returns the fence token if set, 0 if not and -1 if the optional pause key
+// KEYS[3] exists.
+var ObtainFencedScript = &Script{Name: "obtain-fenced", Source: LuaObtainFencedScript}
+
+// fenceSuffix ends the keys of fence counters. Sweep, Keys and Inspect skip
+// keys ending with it, lock keys must not end with it.
+const fenceSuffix = ":__fence__"
+
+// FenceKey returns the redis key of the fence counter of the lock key. It is
+// stored inside the prefix of WithKeyPrefix, ends with ":__fence__" and hashes
+// to the same redis cluster slot as the lock.
+func (c *Client) FenceKey(key string) string {
+	return c.fenceKey(c.redisKey(key))
+}
+
+// fenceKey returns the key of the fence counter of the lock stored under
+// redisKey.
+func (c *Client) fenceKey(redisKey string) string {
+	if _, ok := hashTag(redisKey); ok {
+		return redisKey + fenceSuffix
+	}
+	return c.keyPrefix + "{" + redisKey + "}" + fenceSuffix
+}
+
+// isFenceKey reports whether redisKey is the key of a fence counter.
+func isFenceKey(redisKey string) bool {
+	return strings.HasSuffix(redisKey, fenceSuffix)
+}
+
+func (c *Client) obtainFenced(key, value, ttl string) (int64, error) {
+	keys := []string{key, c.fenceKey(key)}
+	if c.pauseKey != "" {
+		keys = append(keys, c.pauseKey)
+	}
+
+	res, err := c.runScript(ObtainFencedScript, keys, value, ttl)
+	if err != nil {
+		return 0, err
+	} else if res == int64(-1) {
+		return 0, ErrLockingPaused
+	}
+	fence, _ := res.(int64)
+	return fence, nil
+}
+
+// FenceToken returns the fencing token of the lock, which strictly increases
+// with every grant of its key. Pass it along with writes to downstream
+// systems, so they can reject writes of earlier holders whose lock expired.
+// Returns 0 unless the lock was obtained with the Fence option.
+func (l *Lock) FenceToken() int64 {
+	return l.fence
+}
@@ -1,10 +1,26 @@
 module github.com/dineshgowda24/redislock
 
-go 1.14
+go 1.21
 
 require (
-	github.com/garyburd/redigo v1.6.0
-	github.com/go-redis/redis/v7 v7.2.0
-	github.com/onsi/ginkgo v1.10.1
-	github.com/onsi/gomega v1.7.0
+	github.com/gomodule/redigo v1.9.2
+	github.com/onsi/ginkgo v1.16.4
+	github.com/onsi/gomega v1.34.1
+	github.com/redis/go-redis/v9 v9.7.3
+	github.com/redis/rueidis v1.0.50
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/nxadm/tail v1.4.8 // indirect
+	golang.org/x/exp v0.0.0-20240719175910-8a7402abbf56 // indirect
+	golang.org/x/net v0.27.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	golang.org/x/text v0.16.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
+	gopkg.in/tomb.v1 v1.0.0-20141024135613-dd632973f1e7 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -0,0 +1,144 @@
+package redislock
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var errInvalidLock = errors.New("redislock: invalid marshalled lock")
+
+// lockKindNames are the names of the lock kinds in marshalled locks, which
+// must not change.
+var lockKindNames = map[lockKind]string{
+	exclusiveLock: "exclusive",
+	reentrantLock: "reentrant",
+	readLock:      "read",
+	writeLock:     "write",
+	fencedLock:    "fenced",
+	semaphoreLock: "semaphore",
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (k lockKind) MarshalText() ([]byte, error) {
+	name, ok := lockKindNames[k]
+	if !ok {
+		return nil, errInvalidLock
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (k *lockKind) UnmarshalText(data []byte) error {
+	for kind, name := range lockKindNames {
+		if name == string(data) {
+			*k = kind
+			return nil
+		}
+	}
+	return errInvalidLock
+}
+
+// lockState is the serialized form of a Lock.
+type lockState struct {
+	Kind         lockKind      `json:"kind"`
+	Key          string        `json:"key"`
+	Value        string        `json:"value"`
+	Fence        int64         `json:"fence,omitempty"`
+	MaxRefreshes int           `json:"max_refreshes,omitempty"`
+	MaxExtension time.Duration `json:"max_extension,omitempty"`
+	Refreshes    int           `json:"refreshes,omitempty"`
+	ObtainedAt   time.Time     `json:"obtained_at"`
+	TTL          time.Duration `json:"ttl"`
+	ExpiresAt    time.Time     `json:"expires_at"`
+}
+
+// Marshal serializes the lock, e.g. to hand it off through a job queue to a
+// worker which refreshes and releases it, see Client.UnmarshalLock. The
+// refresh policy is preserved. Auto refreshes are not, they continue in this
+// process until the lock is released.
+func (l *Lock) Marshal() ([]byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return json.Marshal(lockState{
+		Kind:         l.kind,
+		Key:          l.key,
+		Value:        l.value,
+		Fence:        l.fence,
+		MaxRefreshes: l.maxRefreshes,
+		MaxExtension: l.maxExtension,
+		Refreshes:    l.refreshes,
+		ObtainedAt:   l.obtainedAt,
+		TTL:          l.ttl,
+		ExpiresAt:    l.expiresAt,
+	})
+}
+
+// UnmarshalLock restores a lock serialized by Lock.Marshal. The restored lock
+// is held by c, it does not check whether the lock is still held.
+func (c *Client) UnmarshalLock(data []byte) (*Lock, error) {
+	state := lockState{Kind: -1}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	if state.Key == "" || state.Value == "" || state.Kind < 0 {
+		return nil, errInvalidLock
+	}
+
+	lock := &Lock{
+		client:       c,
+		kind:         state.Kind,
+		key:          state.Key,
+		value:        state.Value,
+		fence:        state.Fence,
+		maxRefreshes: state.MaxRefreshes,
+		maxExtension: state.MaxExtension,
+		refreshes:    state.Refreshes,
+		obtainedAt:   state.ObtainedAt,
+		ttl:          state.TTL,
+		expiresAt:    state.ExpiresAt,
+	}
+	c.track(lock)
+	return lock, nil
+}
+
+// LockFromToken restores the exclusive lock key held by token, as returned
+// by Lock.Token, including its metadata. Keys without an expiry are
+// considered held until released. Use Lock.Marshal to hand off other
+// kinds of locks or to preserve the fence token and refresh policy.
+// May return ErrLockNotHeld if the lock is not held by token or
+// ErrNotSupported if the backend does not implement Scanner.
+func (c *Client) LockFromToken(key, token string) (*Lock, error) {
+	scanner, ok := c.backend.(Scanner)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	key = c.redisKey(key)
+	value, err := scanner.Get(key)
+	if err != nil {
+		return nil, err
+	} else if value == "" || decodeValue(value).Token != token {
+		return nil, ErrLockNotHeld
+	}
+
+	ttl, err := c.backend.TTL(key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &Lock{
+		client:     c,
+		kind:       exclusiveLock,
+		key:        key,
+		value:      value,
+		obtainedAt: time.Now(),
+	}
+	if ttl > 0 {
+		lock.ttl = time.Duration(ttl) * time.Millisecond
+		lock.expiresAt = lock.obtainedAt.Add(lock.ttl)
+	}
+	c.track(lock)
+	return lock, nil
+}
@@ -0,0 +1,57 @@
+package redislock
+
+import "time"
+
+// Hooks observes lock operations, e.g. to record metrics or tracing spans.
+// Keys are passed as given to Obtain. Hooks are called synchronously by the
+// operation they observe and must not block. Embed NoopHooks to implement a
+// subset of the methods.
+type Hooks interface {
+	// OnObtainAttempt is called before every attempt to obtain a lock,
+	// starting at 1.
+	OnObtainAttempt(key string, attempt int)
+
+	// OnContention is called when an attempt failed because the lock is held
+	// and the obtain retries after at most backoff.
+	OnContention(key string, attempt int, backoff time.Duration)
+
+	// OnObtain is called when a lock was obtained, with the number of
+	// attempts and the time spent waiting for it.
+	OnObtain(key string, attempts int, wait time.Duration)
+
+	// OnObtainFailed is called when an obtain gave up, with
+	// ErrNotObtained, the error of the last attempt or ctx.Err().
+	OnObtainFailed(key string, attempts int, wait time.Duration, err error)
+
+	// OnRefresh is called after every refresh, including auto refreshes.
+	OnRefresh(key string, ttl time.Duration, err error)
+
+	// OnRelease is called after every release, with the time the lock was
+	// held for.
+	OnRelease(key string, held time.Duration, err error)
+
+	// OnLost is called when an auto refreshed lock was lost.
+	OnLost(key string, err error)
+}
+
+// NoopHooks implements Hooks without doing anything.
+type NoopHooks struct{}
+
+func (NoopHooks) OnObtainAttempt(string, int)                      {}
+func (NoopHooks) OnContention(string, int, time.Duration)          {}
+func (NoopHooks) OnObtain(string, int, time.Duration)              {}
+func (NoopHooks) OnObtainFailed(string, int, time.Duration, error) {}
+func (NoopHooks) OnRefresh(string, time.Duration, error)           {}
+func (NoopHooks) OnRelease(string, time.Duration, error)           {}
+func (NoopHooks) OnLost(string, error)                             {}
+
+// WithHooks installs hooks observing the lock operations of the client. A nil
+// h removes installed hooks.
+func WithHooks(h Hooks) ClientOption {
+	return func(c *Client) {
+		if h == nil {
+			h = NoopHooks{}
+		}
+		c.hooks = h
+	}
+}
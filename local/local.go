@@ -0,0 +1,519 @@
+// Package local implements a process-local backend for redislock. Locks are
+// kept in memory with the same TTL semantics as redis, which allows services
+// to run locally and in CI without a redis server:
+//
+//	locker := redislock.New(local.New())
+//
+// Locks are only shared between clients using the same local.Client.
+package local
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+)
+
+// sweepEvery is the number of writes after which expired keys are purged.
+const sweepEvery = 1024
+
+// Client implements redislock.Backend in memory.
+type Client struct {
+	mu     sync.Mutex
+	keys   map[string]entry
+	subs   map[*subscription]struct{}
+	writes int
+	now    func() time.Time
+}
+
+type subscription struct {
+	patterns []*regexp.Regexp
+	ch       chan struct{}
+}
+
+type entry struct {
+	value     string
+	holds     map[string]int64     // hold counts of reentrant locks
+	members   map[string]time.Time // holders of read/write locks and semaphores
+	expiresAt time.Time            // zero for no expiry
+}
+
+func (e entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// New creates a new, empty local client.
+func New() *Client {
+	return NewWithClock(time.Now)
+}
+
+// NewWithClock creates a new, empty local client which expires keys according
+// to the given clock, e.g. a fake clock in tests.
+func NewWithClock(now func() time.Time) *Client {
+	return &Client{keys: make(map[string]entry), subs: make(map[*subscription]struct{}), now: now}
+}
+
+// SetNX sets key to value if the key does not exist.
+func (c *Client) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, ok := c.get(key, now); ok {
+		return false, nil
+	}
+
+	e := entry{value: value}
+	if ttl > 0 {
+		e.expiresAt = now.Add(ttl)
+	}
+	c.keys[key] = e
+
+	if c.writes++; c.writes%sweepEvery == 0 {
+		c.sweep(now)
+	}
+	return true, nil
+}
+
+// Refresh sets the TTL of key to ttl milliseconds if it holds value.
+func (c *Client) Refresh(key, value string, ttl string) error {
+	ms, err := strconv.ParseInt(ttl, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	e, ok := c.get(key, now)
+	if !ok || e.value != value {
+		return redislock.ErrNotObtained
+	}
+
+	if ms <= 0 {
+		delete(c.keys, key)
+		return nil
+	}
+	e.expiresAt = now.Add(time.Duration(ms) * time.Millisecond)
+	c.keys[key] = e
+	return nil
+}
+
+// Release deletes key if it holds value.
+func (c *Client) Release(key, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.get(key, c.now())
+	if !ok || e.value != value {
+		return redislock.ErrLockNotHeld
+	}
+	delete(c.keys, key)
+	return nil
+}
+
+// TTL returns the remaining TTL of key in milliseconds if it holds value or
+// -1 if key has no expiry. It returns redislock.ErrLockNotHeld if key does not
+// hold value.
+func (c *Client) TTL(key, value string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	e, ok := c.get(key, now)
+	if !ok || e.value != value {
+		return 0, redislock.ErrLockNotHeld
+	}
+	if e.expiresAt.IsZero() {
+		return -1, nil
+	}
+	return int64(e.expiresAt.Sub(now) / time.Millisecond), nil
+}
+
+// Delete deletes keys regardless of their value and returns the number of
+// deleted keys.
+func (c *Client) Delete(keys ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	n := 0
+	for _, key := range keys {
+		if _, ok := c.get(key, now); ok {
+			delete(c.keys, key)
+			n++
+		}
+	}
+	return n
+}
+
+// Scan returns all keys matching the glob-style pattern, none for an empty
+// pattern.
+func (c *Client) Scan(match string) ([]string, error) {
+	if match == "" {
+		return nil, nil
+	}
+
+	re, err := compileGlob(match)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	var keys []string
+	for key, e := range c.keys {
+		if !e.expired(now) && re.MatchString(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Get returns the value of key or an empty string if key does not exist.
+func (c *Client) Get(key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, _ := c.get(key, c.now())
+	return e.value, nil
+}
+
+// RunScript runs a native implementation of the redislock scripts.
+// May return redislock.ErrNotSupported for unknown scripts.
+func (c *Client) RunScript(script *redislock.Script, keys []string, args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	switch script.Name {
+	case redislock.ObtainScript.Name:
+		if _, ok := c.get(keys[1], now); ok {
+			return int64(-1), nil
+		}
+		if _, ok := c.get(keys[0], now); ok {
+			return int64(0), nil
+		}
+		c.set(keys[0], args[0], args[1], now)
+		return int64(1), nil
+	case redislock.ObtainFencedScript.Name:
+		if len(keys) > 2 {
+			if _, ok := c.get(keys[2], now); ok {
+				return int64(-1), nil
+			}
+		}
+		if _, ok := c.get(keys[0], now); ok {
+			return int64(0), nil
+		}
+		c.set(keys[0], args[0], args[1], now)
+		e, _ := c.get(keys[1], now)
+		n, err := strconv.ParseInt(e.value, 10, 64)
+		if err != nil && e.value != "" {
+			return nil, err
+		}
+		e.value = strconv.FormatInt(n+1, 10)
+		c.keys[keys[1]] = e
+		return n + 1, nil
+	case redislock.ObtainMultiScript.Name:
+		n, _ := strconv.Atoi(args[2])
+		if len(keys) > n {
+			if _, ok := c.get(keys[n], now); ok {
+				return int64(-1), nil
+			}
+		}
+		for _, key := range keys[:n] {
+			if _, ok := c.get(key, now); ok {
+				return int64(0), nil
+			}
+		}
+		for _, key := range keys[:n] {
+			c.set(key, args[0], args[1], now)
+		}
+		return int64(1), nil
+	case redislock.ReleasePublishScript.Name:
+		e, ok := c.get(keys[0], now)
+		if !ok || e.value != args[0] {
+			return int64(0), nil
+		}
+		delete(c.keys, keys[0])
+		c.publish(args[1])
+		return int64(1), nil
+	case redislock.PauseScript.Name:
+		c.set(keys[0], "1", args[0], now)
+		return int64(1), nil
+	case redislock.ResumeScript.Name:
+		if _, ok := c.get(keys[0], now); ok {
+			delete(c.keys, keys[0])
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case redislock.PausedScript.Name:
+		if _, ok := c.get(keys[0], now); ok {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case redislock.ReentrantObtainScript.Name:
+		if len(keys) > 1 {
+			if _, ok := c.get(keys[1], now); ok {
+				return int64(-1), nil
+			}
+		}
+		e, ok := c.get(keys[0], now)
+		if ok && e.holds[args[0]] == 0 {
+			return int64(0), nil
+		} else if !ok {
+			e = entry{holds: make(map[string]int64)}
+		}
+		e.holds[args[0]]++
+		ms, _ := strconv.ParseInt(args[1], 10, 64)
+		if expiresAt := now.Add(time.Duration(ms) * time.Millisecond); e.expiresAt.IsZero() || e.expiresAt.Before(expiresAt) {
+			e.expiresAt = expiresAt
+		}
+		c.keys[keys[0]] = e
+		return e.holds[args[0]], nil
+	case redislock.ReentrantRefreshScript.Name:
+		e, ok := c.holder(keys[0], args[0], now)
+		if !ok {
+			return int64(0), nil
+		}
+		ms, _ := strconv.ParseInt(args[1], 10, 64)
+		if ms <= 0 {
+			delete(c.keys, keys[0])
+			return int64(1), nil
+		}
+		e.expiresAt = now.Add(time.Duration(ms) * time.Millisecond)
+		c.keys[keys[0]] = e
+		return int64(1), nil
+	case redislock.ReentrantReleaseScript.Name:
+		e, ok := c.holder(keys[0], args[0], now)
+		if !ok {
+			return int64(-1), nil
+		}
+		e.holds[args[0]]--
+		n := e.holds[args[0]]
+		if n <= 0 {
+			delete(c.keys, keys[0])
+		}
+		return n, nil
+	case redislock.ReentrantPTTLScript.Name:
+		e, ok := c.holder(keys[0], args[0], now)
+		if !ok {
+			return int64(-3), nil
+		} else if e.expiresAt.IsZero() {
+			return int64(-1), nil
+		}
+		return int64(e.expiresAt.Sub(now) / time.Millisecond), nil
+	case redislock.ObtainReadScript.Name, redislock.ObtainWriteScript.Name:
+		if len(keys) > 1 {
+			if _, ok := c.get(keys[1], now); ok {
+				return int64(-1), nil
+			}
+		}
+		e, ok := c.rw(keys[0], now)
+		if ok && e.members == nil {
+			return int64(0), nil
+		}
+
+		member := "r:" + args[0]
+		if script.Name == redislock.ObtainWriteScript.Name {
+			if len(e.members) != 0 {
+				return int64(0), nil
+			}
+			member = "w:" + args[0]
+		}
+		for m := range e.members {
+			if strings.HasPrefix(m, "w:") {
+				return int64(0), nil
+			}
+		}
+
+		if e.members == nil {
+			e = entry{members: make(map[string]time.Time)}
+		}
+		c.hold(keys[0], e, member, args[1], now)
+		return int64(1), nil
+	case redislock.ObtainSemaphoreScript.Name:
+		if len(keys) > 1 {
+			if _, ok := c.get(keys[1], now); ok {
+				return int64(-1), nil
+			}
+		}
+		e, ok := c.rw(keys[0], now)
+		if ok && e.members == nil {
+			return int64(0), nil
+		}
+		if limit, _ := strconv.Atoi(args[2]); len(e.members) >= limit {
+			return int64(0), nil
+		}
+
+		if e.members == nil {
+			e = entry{members: make(map[string]time.Time)}
+		}
+		c.hold(keys[0], e, args[0], args[1], now)
+		return int64(1), nil
+	case redislock.RWRefreshScript.Name:
+		e, _ := c.rw(keys[0], now)
+		if _, ok := e.members[args[0]]; !ok {
+			return int64(0), nil
+		}
+		c.hold(keys[0], e, args[0], args[1], now)
+		return int64(1), nil
+	case redislock.RWReleaseScript.Name:
+		e, _ := c.rw(keys[0], now)
+		if _, ok := e.members[args[0]]; !ok {
+			return int64(0), nil
+		}
+		delete(e.members, args[0])
+		if len(e.members) == 0 {
+			delete(c.keys, keys[0])
+		}
+		return int64(1), nil
+	case redislock.RWPTTLScript.Name:
+		e, _ := c.rw(keys[0], now)
+		expiresAt, ok := e.members[args[0]]
+		if !ok {
+			return int64(-3), nil
+		}
+		return int64(expiresAt.Sub(now) / time.Millisecond), nil
+	}
+	return nil, redislock.ErrNotSupported
+}
+
+// PSubscribe subscribes to messages published by the release notifications of
+// this client.
+func (c *Client) PSubscribe(patterns ...string) (<-chan struct{}, func() error, error) {
+	sub := &subscription{ch: make(chan struct{}, 1)}
+	for _, pattern := range patterns {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		sub.patterns = append(sub.patterns, re)
+	}
+
+	c.mu.Lock()
+	c.subs[sub] = struct{}{}
+	c.mu.Unlock()
+
+	unsubscribe := func() error {
+		c.mu.Lock()
+		delete(c.subs, sub)
+		c.mu.Unlock()
+		return nil
+	}
+	return sub.ch, unsubscribe, nil
+}
+
+// publish notifies all subscriptions matching channel.
+func (c *Client) publish(channel string) {
+	for sub := range c.subs {
+		for _, re := range sub.patterns {
+			if re.MatchString(channel) {
+				select {
+				case sub.ch <- struct{}{}:
+				default:
+				}
+				break
+			}
+		}
+	}
+}
+
+// rw returns the entry of key with expired holders of read/write locks
+// removed.
+func (c *Client) rw(key string, now time.Time) (entry, bool) {
+	e, ok := c.get(key, now)
+	for m, expiresAt := range e.members {
+		if !now.Before(expiresAt) {
+			delete(e.members, m)
+		}
+	}
+	return e, ok
+}
+
+// hold sets the expiry of member of the read/write lock key to ttl
+// milliseconds and extends the expiry of the key if necessary.
+func (c *Client) hold(key string, e entry, member, ttl string, now time.Time) {
+	ms, _ := strconv.ParseInt(ttl, 10, 64)
+	expiresAt := now.Add(time.Duration(ms) * time.Millisecond)
+	e.members[member] = expiresAt
+	if e.expiresAt.Before(expiresAt) {
+		e.expiresAt = expiresAt
+	}
+	c.keys[key] = e
+}
+
+// holder returns the entry of the reentrant lock key if held by owner.
+func (c *Client) holder(key, owner string, now time.Time) (entry, bool) {
+	e, ok := c.get(key, now)
+	if !ok || e.holds[owner] == 0 {
+		return entry{}, false
+	}
+	return e, true
+}
+
+// set stores value under key with a TTL of ttl milliseconds, or without
+// expiry if ttl is not positive.
+func (c *Client) set(key, value, ttl string, now time.Time) {
+	e := entry{value: value}
+	if ms, _ := strconv.ParseInt(ttl, 10, 64); ms > 0 {
+		e.expiresAt = now.Add(time.Duration(ms) * time.Millisecond)
+	}
+	c.keys[key] = e
+}
+
+// get returns the entry of key, expiring it if necessary.
+func (c *Client) get(key string, now time.Time) (entry, bool) {
+	e, ok := c.keys[key]
+	if ok && e.expired(now) {
+		delete(c.keys, key)
+		return entry{}, false
+	}
+	return e, ok
+}
+
+func (c *Client) sweep(now time.Time) {
+	for key, e := range c.keys {
+		if e.expired(now) {
+			delete(c.keys, key)
+		}
+	}
+}
+
+// compileGlob translates a redis glob-style pattern into a regexp.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			b.WriteString("(?s:.*)")
+		case '?':
+			b.WriteString("(?s:.)")
+		case '[':
+			end := strings.IndexByte(pattern[i:], ']')
+			if end < 0 {
+				b.WriteString(regexp.QuoteMeta(pattern[i:]))
+				i = len(pattern)
+				continue
+			}
+			b.WriteString(pattern[i : i+end+1])
+			i += end
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
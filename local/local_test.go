@@ -0,0 +1,386 @@
+package local_test
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/dineshgowda24/redislock/local"
+	"github.com/dineshgowda24/redislock/locktest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lockKey = "__bsm_redislock_unit_test__"
+
+var _ = Describe("Client", func() {
+	var backend *local.Client
+	var subject *redislock.Client
+
+	BeforeEach(func() {
+		backend = local.New()
+		subject = redislock.New(backend)
+	})
+
+	It("should implement the backend contract", func() {
+		Expect(locktest.CheckBackend(backend, lockKey)).To(Succeed())
+	})
+
+	It("should obtain once with TTL", func() {
+		lock1, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock1.Token()).To(HaveLen(22))
+		Expect(lock1.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		defer lock1.Release()
+
+		_, err = subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+		Expect(lock1.Release()).To(Succeed())
+
+		lock2, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock2.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock2.Release()).To(Succeed())
+	})
+
+	It("should support custom metadata", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, &redislock.Options{Metadata: "my-data", MetadataMap: map[string]string{"host": "worker-1"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Metadata()).To(Equal("my-data"))
+		Expect(lock.MetadataValue("host")).To(Equal("worker-1"))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should refresh", func() {
+		lock, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should fail to release if expired", func() {
+		lock, err := subject.Obtain(lockKey, time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+		_, err = lock.TTL()
+		Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(lock.Release()).To(MatchError(redislock.ErrLockNotHeld))
+	})
+
+	It("should fail to refresh if expired", func() {
+		lock, err := subject.Obtain(lockKey, time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(5 * time.Millisecond)
+		Expect(lock.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+	})
+
+	It("should share locks between clients of the same backend", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = redislock.New(backend).Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+
+		other, err := redislock.New(local.New()).Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(other.Release()).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support reentrant locks", func() {
+		opt := &redislock.Options{Reentrant: true}
+		outer, err := subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		inner, err := subject.Obtain(lockKey, time.Second, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(inner.Token()).To(Equal(outer.Token()))
+		Expect(inner.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+
+		_, err = redislock.New(backend).Obtain(lockKey, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(inner.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(inner.Release()).To(Succeed())
+		Expect(inner.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(outer.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+		Expect(outer.Release()).To(Succeed())
+		Expect(outer.IsHeld(context.Background())).To(BeFalse())
+
+		lock, err := redislock.New(backend).Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should support fence tokens", func() {
+		opt := &redislock.Options{Fence: true}
+		lock, err := subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.FenceToken()).To(Equal(int64(1)))
+
+		_, err = subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Release()).To(Succeed())
+
+		lock, err = subject.Obtain(lockKey, time.Minute, opt)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.FenceToken()).To(Equal(int64(2)))
+		Expect(lock.Release()).To(Succeed())
+		Expect(backend.Get(subject.FenceKey(lockKey))).To(Equal("2"))
+	})
+
+	It("should support read/write locks", func() {
+		r1, err := subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := subject.ObtainRead(lockKey, time.Hour, &redislock.Options{Metadata: "my-data"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(r2.Metadata()).To(Equal("my-data"))
+		Expect(r1.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(r2.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+
+		Expect(r1.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(r1.Release()).To(Succeed())
+		Expect(r1.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		Expect(r1.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(r2.Release()).To(Succeed())
+
+		w, err := subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		_, err = subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(w.Release()).To(Succeed())
+		Expect(w.IsHeld(context.Background())).To(BeFalse())
+	})
+
+	It("should expire readers individually", func() {
+		r1, err := subject.ObtainRead(lockKey, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		r2, err := subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(50 * time.Millisecond)
+
+		Expect(r1.IsHeld(context.Background())).To(BeFalse())
+		Expect(r2.Release()).To(Succeed())
+		w, err := subject.ObtainWrite(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(w.Release()).To(Succeed())
+	})
+
+	It("should support semaphores", func() {
+		s1, err := subject.ObtainSemaphore(lockKey, 2, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		s2, err := subject.ObtainSemaphore(lockKey, 2, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s1.TTL()).To(BeNumerically("~", time.Minute, time.Second))
+		Expect(s2.TTL()).To(BeNumerically("~", time.Hour, time.Second))
+
+		_, err = subject.ObtainSemaphore(lockKey, 2, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		_, err = subject.ObtainSemaphore(lockKey, 0, time.Minute, nil)
+		Expect(err).To(HaveOccurred())
+
+		Expect(s1.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(s1.Release()).To(Succeed())
+		Expect(s1.Release()).To(MatchError(redislock.ErrLockNotHeld))
+		s3, err := subject.ObtainSemaphore(lockKey, 2, 20*time.Millisecond, nil)
+		Expect(err).NotTo(HaveOccurred())
+		time.Sleep(50 * time.Millisecond)
+
+		Expect(s3.IsHeld(context.Background())).To(BeFalse())
+		s4, err := subject.ObtainSemaphore(lockKey, 2, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s4.Release()).To(Succeed())
+		Expect(s2.Release()).To(Succeed())
+	})
+
+	It("should obtain multiple keys", func() {
+		keys := []string{lockKey + "a", lockKey + "b", "{" + lockKey + "}c"}
+		lock, err := subject.Obtain(keys[1], time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = subject.ObtainMulti(keys, time.Minute, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(backend.Scan(lockKey + "*")).To(ConsistOf(keys[1]))
+		Expect(lock.Release()).To(Succeed())
+
+		multi, err := subject.ObtainMulti(keys, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(multi.Locks()).To(HaveLen(3))
+		Expect(backend.Scan("*")).To(ConsistOf(keys))
+		Expect(multi.Release()).To(Succeed())
+		Expect(backend.Scan("*")).To(BeEmpty())
+	})
+
+	It("should wake up waiters on release", func() {
+		client := redislock.New(backend, redislock.WithReleaseNotifications())
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		obtained := make(chan *redislock.Lock, 1)
+		go func() {
+			defer GinkgoRecover()
+			lock, err := client.Obtain(lockKey, time.Hour, &redislock.Options{
+				RetryStrategy: redislock.LinearBackoff(time.Minute),
+				ObtainTimeout: time.Minute,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			obtained <- lock
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		Expect(lock.Release()).To(Succeed())
+		Eventually(obtained, time.Second).Should(Receive(&lock))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should fail fast while paused", func() {
+		client := redislock.New(backend, redislock.WithPauseKey(redislock.DefaultPauseKey))
+		Expect(client.Pause(0)).To(Succeed())
+		_, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrLockingPaused))
+		Expect(client.Resume()).To(Succeed())
+
+		lock, err := client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = client.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should scan keys", func() {
+		for _, key := range []string{"locks:a", "locks:b", "locks:c1", "other"} {
+			Expect(backend.SetNX(key, "value", time.Minute)).To(BeTrue())
+		}
+		Expect(backend.SetNX("locks:expired", "value", time.Millisecond)).To(BeTrue())
+		time.Sleep(5 * time.Millisecond)
+
+		Expect(backend.Scan("locks:*")).To(ConsistOf("locks:a", "locks:b", "locks:c1"))
+		Expect(backend.Scan("locks:?")).To(ConsistOf("locks:a", "locks:b"))
+		Expect(backend.Scan("locks:[^a]*")).To(ConsistOf("locks:b", "locks:c1"))
+		Expect(backend.Get("locks:a")).To(Equal("value"))
+		Expect(backend.Get("locks:expired")).To(BeEmpty())
+	})
+
+	It("should prevent multiple locks (fuzzing)", func() {
+		numLocks := int32(0)
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 1000; i++ {
+			wg.Add(1)
+
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+
+				wait := rand.Int63n(int64(50 * time.Millisecond))
+				time.Sleep(time.Duration(wait))
+
+				_, err := subject.Obtain(lockKey, time.Minute, nil)
+				if err == redislock.ErrNotObtained {
+					return
+				}
+				Expect(err).NotTo(HaveOccurred())
+				atomic.AddInt32(&numLocks, 1)
+			}()
+		}
+		wg.Wait()
+		Expect(numLocks).To(Equal(int32(1)))
+	})
+})
+
+var _ = Describe("Quorum", func() {
+	var nodes []*local.Client
+	var subject *redislock.Client
+
+	BeforeEach(func() {
+		nodes = []*local.Client{local.New(), local.New(), local.New()}
+		subject = redislock.NewMulti(nodes[0], nodes[1], nodes[2])
+	})
+
+	It("should implement the backend contract", func() {
+		Expect(locktest.CheckBackend(redislock.NewQuorum(nodes[0], nodes[1], nodes[2]), lockKey)).To(Succeed())
+	})
+
+	It("should obtain and release on all nodes", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		for _, node := range nodes {
+			Expect(node.TTL(lockKey, lock.Token())).To(BeNumerically("~", time.Hour.Milliseconds(), 1000))
+		}
+		Expect(lock.TTL()).To(BeNumerically("<", time.Hour-30*time.Second))
+
+		Expect(lock.Refresh(2*time.Hour, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+		for _, node := range nodes {
+			_, err := node.TTL(lockKey, lock.Token())
+			Expect(err).To(MatchError(redislock.ErrLockNotHeld))
+		}
+	})
+
+	It("should require a majority", func() {
+		Expect(nodes[0].SetNX(lockKey, "other", time.Hour)).To(BeTrue())
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(nodes[1].Release(lockKey, lock.Token())).To(Succeed())
+		Expect(lock.IsHeld(context.Background())).To(BeFalse())
+		Expect(lock.Refresh(time.Hour, nil)).To(MatchError(redislock.ErrNotObtained))
+		Expect(lock.Release()).To(MatchError(redislock.ErrLockNotHeld))
+
+		Expect(nodes[1].SetNX(lockKey, "other", time.Hour)).To(BeTrue())
+		_, err = subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+		Expect(nodes[2].Get(lockKey)).To(BeEmpty())
+	})
+
+	It("should tolerate a minority of unavailable nodes", func() {
+		subject = redislock.NewMulti(nodes[0], nodes[1], unavailable{})
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+
+		subject = redislock.NewMulti(nodes[0], unavailable{}, unavailable{})
+		_, err = subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(MatchError(errUnavailable))
+		Expect(nodes[0].Get(lockKey)).To(BeEmpty())
+	})
+
+	It("should not obtain locks shorter than the clock drift", func() {
+		_, err := subject.Obtain(lockKey, time.Millisecond, nil)
+		Expect(err).To(MatchError(redislock.ErrNotObtained))
+	})
+})
+
+// --------------------------------------------------------------------
+
+var errUnavailable = errors.New("unavailable")
+
+type unavailable struct{}
+
+func (unavailable) SetNX(_, _ string, _ time.Duration) (bool, error) { return false, errUnavailable }
+func (unavailable) Refresh(_, _ string, _ string) error              { return errUnavailable }
+func (unavailable) Release(_, _ string) error                        { return errUnavailable }
+func (unavailable) TTL(_, _ string) (int64, error)                   { return 0, errUnavailable }
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "local")
+}
@@ -0,0 +1,78 @@
+package locktest
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+)
+
+// CheckBackend verifies that b implements the redislock.Backend contract,
+// using key as a scratch key which must not be in use. It returns the first
+// deviation found.
+func CheckBackend(b redislock.Backend, key string) error {
+	const value, other = "locktest-value", "locktest-other"
+
+	if ok, err := b.SetNX(key, value, time.Minute); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("locktest: SetNX did not store free key %q", key)
+	}
+	defer b.Release(key, value)
+
+	if ok, err := b.SetNX(key, other, time.Minute); err != nil {
+		return err
+	} else if ok {
+		return fmt.Errorf("locktest: SetNX stored held key %q", key)
+	}
+
+	if res, err := b.TTL(key, value); err != nil {
+		return err
+	} else if res <= 0 || res > time.Minute.Milliseconds() {
+		return fmt.Errorf("locktest: TTL returned %d, expected up to %d", res, time.Minute.Milliseconds())
+	}
+	if _, err := b.TTL(key, other); err != redislock.ErrLockNotHeld {
+		return fmt.Errorf("locktest: TTL of foreign value returned %v, expected ErrLockNotHeld", err)
+	}
+
+	if err := b.Refresh(key, other, "120000"); err != redislock.ErrNotObtained {
+		return fmt.Errorf("locktest: Refresh of foreign value returned %v, expected ErrNotObtained", err)
+	}
+	if err := b.Refresh(key, value, "120000"); err != nil {
+		return err
+	}
+	if res, err := b.TTL(key, value); err != nil {
+		return err
+	} else if res <= time.Minute.Milliseconds() {
+		return fmt.Errorf("locktest: TTL returned %d after Refresh, expected more than %d", res, time.Minute.Milliseconds())
+	}
+
+	if err := b.Release(key, other); err != redislock.ErrLockNotHeld {
+		return fmt.Errorf("locktest: Release of foreign value returned %v, expected ErrLockNotHeld", err)
+	}
+	if err := b.Release(key, value); err != nil {
+		return err
+	}
+	if err := b.Release(key, value); err != redislock.ErrLockNotHeld {
+		return fmt.Errorf("locktest: Release of released key returned %v, expected ErrLockNotHeld", err)
+	}
+	if err := b.Refresh(key, value, "120000"); err != redislock.ErrNotObtained {
+		return fmt.Errorf("locktest: Refresh of released key returned %v, expected ErrNotObtained", err)
+	}
+	if _, err := b.TTL(key, value); err != redislock.ErrLockNotHeld {
+		return fmt.Errorf("locktest: TTL of released key returned %v, expected ErrLockNotHeld", err)
+	}
+
+	if ok, err := b.SetNX(key, value, 10*time.Millisecond); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("locktest: SetNX did not store released key %q", key)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if ok, err := b.SetNX(key, other, time.Minute); err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("locktest: SetNX did not store expired key %q", key)
+	}
+	return b.Release(key, other)
+}
@@ -0,0 +1,317 @@
+// Package locktest provides a concurrency test harness which validates the
+// mutual exclusion and fencing guarantees of a redislock.Client.
+//
+// Run spawns workers which repeatedly obtain the same lock, enter a critical
+// section and write to a shared in-memory store. Faults such as forced
+// expiries or failovers can be injected while the workers are running. The
+// harness records every grant and reports overlapping critical sections which
+// cannot be explained by an injected fault or an expired lease, as well as
+// stale writes and out-of-order fence tokens.
+package locktest
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+)
+
+// Config configures a Run.
+type Config struct {
+	// NewClient returns the client used by a worker. Required.
+	NewClient func(worker int) *redislock.Client
+
+	// Key is the lock key workers contend for.
+	// Default: __redislock_locktest__
+	Key string
+
+	// Workers is the number of concurrent workers.
+	// Default: 8
+	Workers int
+
+	// Duration is the duration of the run.
+	// Default: 1s
+	Duration time.Duration
+
+	// TTL is the lock TTL.
+	// Default: 100ms
+	TTL time.Duration
+
+	// Hold is the time a worker spends in the critical section.
+	// Default: 1ms
+	Hold time.Duration
+
+	// RetryStrategy returns the retry strategy of a single obtain call.
+	// Default: do not retry
+	RetryStrategy func() redislock.RetryStrategy
+
+	// Fence returns the fence token of an obtained lock. Fence tokens must
+	// strictly increase with every grant.
+	// Default: a sequence assigned by the harness
+	Fence func(lock *redislock.Lock) int64
+
+	// Fenced obtains the locks with the Fence option and, unless Fence is
+	// set, checks their fence tokens.
+	Fenced bool
+
+	// Inject, if set, is called every InjectInterval to inject a fault,
+	// e.g. deleting the key or failing over redis.
+	Inject func(key string) error
+
+	// InjectInterval is the interval between injected faults.
+	// Default: 100ms
+	InjectInterval time.Duration
+}
+
+func (c *Config) norm() error {
+	if c.NewClient == nil {
+		return errors.New("locktest: NewClient is required")
+	}
+	if c.Key == "" {
+		c.Key = "__redislock_locktest__"
+	}
+	if c.Workers < 1 {
+		c.Workers = 8
+	}
+	if c.Duration <= 0 {
+		c.Duration = time.Second
+	}
+	if c.TTL <= 0 {
+		c.TTL = 100 * time.Millisecond
+	}
+	if c.Hold <= 0 {
+		c.Hold = time.Millisecond
+	}
+	if c.RetryStrategy == nil {
+		c.RetryStrategy = redislock.NoRetry
+	}
+	if c.Fenced && c.Fence == nil {
+		c.Fence = (*redislock.Lock).FenceToken
+	}
+	if c.InjectInterval <= 0 {
+		c.InjectInterval = 100 * time.Millisecond
+	}
+	return nil
+}
+
+// Violation describes two critical sections which overlapped while the lease
+// of the earlier holder was known to be valid.
+type Violation struct {
+	// Holder is the worker which held the lock.
+	Holder int
+	// Intruder is the worker which obtained the lock while it was held.
+	Intruder int
+	// At is the time the intruder obtained the lock.
+	At time.Time
+}
+
+// Result summarises a Run.
+type Result struct {
+	// Grants is the number of successful obtains.
+	Grants int
+	// Contended is the number of obtains which failed with ErrNotObtained.
+	Contended int
+	// Errors is the number of obtains which failed with any other error.
+	Errors int
+	// Lost is the number of locks which were no longer held on release.
+	Lost int
+	// Injections is the number of injected faults.
+	Injections int
+
+	// Overlaps is the number of overlapping critical sections, including
+	// those explained by injected faults or expired leases.
+	Overlaps int
+	// Violations are overlaps which violate mutual exclusion.
+	Violations []Violation
+
+	// StaleWrites is the number of writes rejected by the store because a
+	// newer fence token had already been written.
+	StaleWrites int
+	// FenceRegressions is the number of grants whose fence token did not
+	// increase over the previous grant. Grants which may have been reordered
+	// by an injected fault are only checked for repeated tokens.
+	FenceRegressions int
+}
+
+// Err returns an error if the run violated mutual exclusion or fencing order.
+func (r *Result) Err() error {
+	if n := len(r.Violations); n != 0 {
+		return fmt.Errorf("locktest: %d mutual exclusion violation(s), first: worker %d intruded on worker %d", n, r.Violations[0].Intruder, r.Violations[0].Holder)
+	}
+	if r.FenceRegressions != 0 {
+		return fmt.Errorf("locktest: %d fence token regression(s)", r.FenceRegressions)
+	}
+	return nil
+}
+
+// Run runs the harness and returns the result once all workers have stopped.
+func Run(cfg Config) (*Result, error) {
+	if err := cfg.norm(); err != nil {
+		return nil, err
+	}
+
+	h := &harness{cfg: cfg, holder: -1}
+	stop := make(chan struct{})
+	time.AfterFunc(cfg.Duration, func() { close(stop) })
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			h.work(worker, cfg.NewClient(worker), stop)
+		}(i)
+	}
+	if cfg.Inject != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.inject(stop)
+		}()
+	}
+	wg.Wait()
+
+	return &h.res, nil
+}
+
+type harness struct {
+	cfg Config
+
+	mu           sync.Mutex
+	res          Result
+	seq          int64
+	lastFence    int64
+	stored       int64
+	injecting    bool
+	lastInjected time.Time
+
+	// current holder of the critical section
+	holder      int
+	holderStart time.Time
+}
+
+func (h *harness) work(worker int, client *redislock.Client, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		start := time.Now()
+		lock, err := client.Obtain(h.cfg.Key, h.cfg.TTL, &redislock.Options{RetryStrategy: h.cfg.RetryStrategy(), Fence: h.cfg.Fenced})
+		if err == redislock.ErrNotObtained {
+			h.record(func(r *Result) { r.Contended++ })
+			continue
+		} else if err != nil {
+			h.record(func(r *Result) { r.Errors++ })
+			continue
+		}
+
+		fence := h.enter(worker, lock, start)
+		h.write(fence)
+		time.Sleep(h.cfg.Hold)
+		h.write(fence)
+		h.leave(worker)
+
+		if err := lock.Release(); err == redislock.ErrLockNotHeld {
+			h.record(func(r *Result) { r.Lost++ })
+		} else if err != nil {
+			h.record(func(r *Result) { r.Errors++ })
+		}
+	}
+}
+
+// enter marks worker as the holder of the critical section and returns the
+// fence token of the grant.
+func (h *harness) enter(worker int, lock *redislock.Lock, start time.Time) int64 {
+	now := time.Now()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.res.Grants++
+	h.seq++
+	fence := h.seq
+	if h.cfg.Fence != nil {
+		fence = h.cfg.Fence(lock)
+	}
+	// Workers may enter in a different order than they were granted the lock
+	// if a fault was injected since they started obtaining it, the fence token
+	// must still never repeat.
+	reordered := h.injecting || !h.lastInjected.Before(start)
+	if fence == h.lastFence || (fence < h.lastFence && !reordered) {
+		h.res.FenceRegressions++
+	}
+	if fence > h.lastFence {
+		h.lastFence = fence
+	}
+
+	if h.holder != -1 {
+		h.res.Overlaps++
+
+		// The lease of the previous holder was set after holderStart, it is
+		// certainly valid unless a fault was injected in the meantime.
+		injected := h.injecting || !h.lastInjected.Before(h.holderStart)
+		if !injected && now.Before(h.holderStart.Add(h.cfg.TTL)) {
+			h.res.Violations = append(h.res.Violations, Violation{Holder: h.holder, Intruder: worker, At: now})
+		}
+	}
+	h.holder = worker
+	h.holderStart = start
+	return fence
+}
+
+func (h *harness) leave(worker int) {
+	h.mu.Lock()
+	if h.holder == worker {
+		h.holder = -1
+	}
+	h.mu.Unlock()
+}
+
+// write simulates a fenced write to a downstream store.
+func (h *harness) write(fence int64) {
+	h.mu.Lock()
+	if fence < h.stored {
+		h.res.StaleWrites++
+	} else {
+		h.stored = fence
+	}
+	h.mu.Unlock()
+}
+
+func (h *harness) inject(stop <-chan struct{}) {
+	ticker := time.NewTicker(h.cfg.InjectInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.mu.Lock()
+			h.injecting = true
+			h.mu.Unlock()
+
+			err := h.cfg.Inject(h.cfg.Key)
+
+			h.mu.Lock()
+			h.injecting = false
+			h.lastInjected = time.Now()
+			h.res.Injections++
+			if err != nil {
+				h.res.Errors++
+			}
+			h.mu.Unlock()
+		}
+	}
+}
+
+func (h *harness) record(fn func(*Result)) {
+	h.mu.Lock()
+	fn(&h.res)
+	h.mu.Unlock()
+}
@@ -0,0 +1,54 @@
+package redislock
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// lockValue is the structured form of a lock value with metadata. Values
+// without metadata consist of the token only.
+type lockValue struct {
+	Token       string            `json:"token"`
+	Metadata    string            `json:"metadata,omitempty"`
+	MetadataMap map[string]string `json:"values,omitempty"`
+}
+
+// encodeValue returns the lock value of token with the given metadata.
+func encodeValue(token, metadata string, metadataMap map[string]string) string {
+	if metadata == "" && len(metadataMap) == 0 {
+		return token
+	}
+
+	// cannot fail for strings
+	b, _ := json.Marshal(lockValue{Token: token, Metadata: metadata, MetadataMap: metadataMap})
+	return string(b)
+}
+
+// decodeValue parses a lock value. Unstructured values are returned as token.
+func decodeValue(value string) lockValue {
+	var v lockValue
+	if strings.HasPrefix(value, "{") && json.Unmarshal([]byte(value), &v) == nil && v.Token != "" {
+		return v
+	}
+	return lockValue{Token: value}
+}
+
+// MetadataValue returns the value of key in the MetadataMap the lock was
+// obtained with, or an empty string.
+func (l *Lock) MetadataValue(key string) string {
+	return decodeValue(l.value).MetadataMap[key]
+}
+
+// MetadataMap returns a copy of the MetadataMap the lock was obtained with.
+func (l *Lock) MetadataMap() map[string]string {
+	m := decodeValue(l.value).MetadataMap
+	if m == nil {
+		return nil
+	}
+
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
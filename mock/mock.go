@@ -0,0 +1,198 @@
+// Package mock implements an in-memory backend for unit tests of code using
+// redislock. It behaves like the local backend, but its clock can be advanced
+// to expire keys without waiting and failures can be injected:
+//
+//	backend := mock.New()
+//	locker := redislock.New(backend)
+//
+//	lock, _ := locker.Obtain("key", time.Minute, nil)
+//	backend.Advance(time.Minute)
+//	err := lock.Refresh(time.Minute, nil) // redislock.ErrNotObtained
+//
+// The clock runs in real time on top of the advanced offset, since retries and
+// auto refreshes are still scheduled in real time.
+package mock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/dineshgowda24/redislock/local"
+)
+
+// scripts which obtain or refresh locks
+var (
+	obtainScripts = map[string]bool{
+		redislock.ObtainScript.Name:          true,
+		redislock.ObtainFencedScript.Name:    true,
+		redislock.ObtainMultiScript.Name:     true,
+		redislock.ObtainSemaphoreScript.Name: true,
+		redislock.ObtainReadScript.Name:      true,
+		redislock.ObtainWriteScript.Name:     true,
+		redislock.ReentrantObtainScript.Name: true,
+	}
+	refreshScripts = map[string]bool{
+		redislock.ReentrantRefreshScript.Name: true,
+		redislock.RWRefreshScript.Name:        true,
+	}
+)
+
+// Client implements redislock.Backend in memory with an adjustable clock.
+type Client struct {
+	*local.Client
+
+	mu               sync.Mutex
+	offset           time.Duration
+	forceNotObtained bool
+	refreshErr       error
+	err              error
+}
+
+// New creates a new, empty mock client.
+func New() *Client {
+	c := new(Client)
+	c.Client = local.NewWithClock(c.Now)
+	return c
+}
+
+// Now returns the current time of the clock, the real time plus all
+// advances.
+func (c *Client) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return time.Now().Add(c.offset)
+}
+
+// Advance moves the clock forward by d, which expires all keys whose TTL
+// passes in the meantime.
+func (c *Client) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.offset += d
+	c.mu.Unlock()
+}
+
+// Expire expires keys right away, as if their TTL had passed.
+func (c *Client) Expire(keys ...string) {
+	c.Delete(keys...)
+}
+
+// ForceNotObtained makes all obtains fail as if the keys were held by someone
+// else while enabled.
+func (c *Client) ForceNotObtained(enabled bool) {
+	c.mu.Lock()
+	c.forceNotObtained = enabled
+	c.mu.Unlock()
+}
+
+// FailRefreshes makes all refreshes fail with err until called with nil. Pass
+// redislock.ErrNotObtained to simulate lost locks, any other error to
+// simulate transient failures.
+func (c *Client) FailRefreshes(err error) {
+	c.mu.Lock()
+	c.refreshErr = err
+	c.mu.Unlock()
+}
+
+// SetError makes all operations fail with err until called with nil, e.g. to
+// simulate an unavailable redis server.
+func (c *Client) SetError(err error) {
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+}
+
+// SetNX sets key to value if the key does not exist.
+func (c *Client) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	if err := c.failure(); err != nil {
+		return false, err
+	} else if c.notObtained() {
+		return false, nil
+	}
+	return c.Client.SetNX(key, value, ttl)
+}
+
+// Refresh sets the TTL of key to ttl milliseconds if it holds value.
+func (c *Client) Refresh(key, value string, ttl string) error {
+	if err := c.failure(); err != nil {
+		return err
+	} else if err := c.refreshFailure(); err != nil {
+		return err
+	}
+	return c.Client.Refresh(key, value, ttl)
+}
+
+// Release deletes key if it holds value.
+func (c *Client) Release(key, value string) error {
+	if err := c.failure(); err != nil {
+		return err
+	}
+	return c.Client.Release(key, value)
+}
+
+// TTL returns the remaining TTL of key in milliseconds if it holds value, see
+// redislock.Backend.
+func (c *Client) TTL(key, value string) (int64, error) {
+	if err := c.failure(); err != nil {
+		return 0, err
+	}
+	return c.Client.TTL(key, value)
+}
+
+// Scan returns all keys matching the glob-style pattern.
+func (c *Client) Scan(match string) ([]string, error) {
+	if err := c.failure(); err != nil {
+		return nil, err
+	}
+	return c.Client.Scan(match)
+}
+
+// Get returns the value of key or an empty string if key does not exist.
+func (c *Client) Get(key string) (string, error) {
+	if err := c.failure(); err != nil {
+		return "", err
+	}
+	return c.Client.Get(key)
+}
+
+// RunScript runs a native implementation of the redislock scripts.
+// May return redislock.ErrNotSupported for unknown scripts.
+func (c *Client) RunScript(script *redislock.Script, keys []string, args ...string) (interface{}, error) {
+	if err := c.failure(); err != nil {
+		return nil, err
+	}
+
+	if obtainScripts[script.Name] && c.notObtained() {
+		return int64(0), nil
+	}
+	if refreshScripts[script.Name] {
+		if err := c.refreshFailure(); err == redislock.ErrNotObtained {
+			return int64(0), nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return c.Client.RunScript(script, keys, args...)
+}
+
+func (c *Client) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+func (c *Client) notObtained() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.forceNotObtained
+}
+
+func (c *Client) refreshFailure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.refreshErr
+}
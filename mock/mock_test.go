@@ -0,0 +1,144 @@
+package mock_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dineshgowda24/redislock"
+	"github.com/dineshgowda24/redislock/locktest"
+	"github.com/dineshgowda24/redislock/mock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const lockKey = "__bsm_redislock_unit_test__"
+
+var _ = Describe("Client", func() {
+	var backend *mock.Client
+	var subject *redislock.Client
+
+	BeforeEach(func() {
+		backend = mock.New()
+		subject = redislock.New(backend)
+	})
+
+	It("should implement the backend contract", func() {
+		Expect(locktest.CheckBackend(backend, lockKey)).To(Succeed())
+	})
+
+	It("should expire locks as the clock advances", func() {
+		lock, err := subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.Advance(30 * time.Second)
+		Expect(lock.TTL()).To(BeNumerically("~", 30*time.Second, time.Second))
+		Expect(lock.IsHeld(context.Background())).To(BeTrue())
+
+		backend.Advance(30 * time.Second)
+		Expect(lock.IsHeld(context.Background())).To(BeFalse())
+		Expect(lock.Refresh(time.Minute, nil)).To(Equal(redislock.ErrNotObtained))
+		Expect(lock.Release()).To(Equal(redislock.ErrLockNotHeld))
+
+		lock, err = subject.Obtain(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should expire read locks as the clock advances", func() {
+		lock1, err := subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		backend.Advance(30 * time.Second)
+		lock2, err := subject.ObtainRead(lockKey, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.Advance(30 * time.Second)
+		Expect(lock1.IsHeld(context.Background())).To(BeFalse())
+		Expect(lock2.IsHeld(context.Background())).To(BeTrue())
+		Expect(lock2.Release()).To(Succeed())
+	})
+
+	It("should expire locks on demand", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.Expire(lockKey)
+		Expect(lock.IsHeld(context.Background())).To(BeFalse())
+	})
+
+	It("should force obtains to fail", func() {
+		backend.ForceNotObtained(true)
+		_, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+		_, err = subject.Obtain(lockKey, time.Hour, &redislock.Options{Fence: true})
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+		_, err = subject.ObtainWrite(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+		_, err = subject.ObtainMulti([]string{lockKey, lockKey + "2"}, time.Hour, nil)
+		Expect(err).To(Equal(redislock.ErrNotObtained))
+
+		backend.ForceNotObtained(false)
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should force refreshes to fail", func() {
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.FailRefreshes(redislock.ErrNotObtained)
+		Expect(lock.Refresh(time.Hour, nil)).To(Equal(redislock.ErrNotObtained))
+
+		backend.FailRefreshes(nil)
+		Expect(lock.Refresh(time.Hour, nil)).To(Succeed())
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should lose auto refreshed locks", func() {
+		lock, err := subject.Obtain(lockKey, 30*time.Millisecond, &redislock.Options{AutoRefresh: true})
+		Expect(err).NotTo(HaveOccurred())
+		defer lock.Release()
+
+		backend.FailRefreshes(redislock.ErrNotObtained)
+		Eventually(lock.Lost()).Should(BeClosed())
+		Expect(lock.Err()).To(Equal(redislock.ErrNotObtained))
+	})
+
+	It("should fail all operations", func() {
+		errUnavailable := errors.New("unavailable")
+
+		lock, err := subject.Obtain(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		backend.SetError(errUnavailable)
+		_, err = subject.Obtain(lockKey+"2", time.Hour, nil)
+		Expect(err).To(Equal(errUnavailable))
+		Expect(lock.Refresh(time.Hour, nil)).To(Equal(errUnavailable))
+		Expect(lock.Release()).To(Equal(errUnavailable))
+
+		backend.SetError(nil)
+		Expect(lock.Release()).To(Succeed())
+	})
+
+	It("should retry errors while blocking", func() {
+		backend.SetError(&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+		time.AfterFunc(50*time.Millisecond, func() { backend.SetError(nil) })
+
+		lock, err := subject.ObtainBlocking(lockKey, time.Hour, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lock.Release()).To(Succeed())
+
+		errWrongType := errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+		backend.SetError(errWrongType)
+		_, err = subject.ObtainBlocking(lockKey, time.Hour, nil)
+		Expect(err).To(Equal(errWrongType))
+	})
+})
+
+func TestSuite(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "mock")
+}
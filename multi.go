@@ -0,0 +1,191 @@
+package redislock
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lua script for multi-key locks, run through the Scripter interface
+const LuaObtainMultiScript = `local n = tonumber(ARGV[3]) if KEYS[n + 1] and redis.call("exists", KEYS[n + 1]) == 1 then return -1 end for i = 1, n do if redis.call("exists", KEYS[i]) == 1 then return 0 end end for i = 1, n do redis.call("set", KEYS[i], ARGV[1], "PX", ARGV[2]) end return 1`
+
+// ObtainMultiScript sets the first ARGV[3] keys to ARGV[1] with a TTL of
+// ARGV[2] milliseconds unless any of them exists. Returns 1 if set, 0 if not
+// and -1 if the optional pause key following them exists.
+var ObtainMultiScript = &Script{Name: "obtain-multi", Source: LuaObtainMultiScript}
+
+var errNoKeys = errors.New("redislock: no keys to obtain")
+
+// MultiLock is a set of exclusive locks obtained together by ObtainMulti.
+type MultiLock struct {
+	locks []*Lock
+}
+
+// ObtainMulti tries to obtain exclusive locks on all of the given keys with
+// the given TTL, retrying like Obtain. Either all locks are obtained or none.
+//
+// Keys are grouped by redis cluster hash slot and every group is obtained
+// atomically, in the order of their slots. If a group cannot be obtained, the
+// groups obtained before are released again. Use hash tags, e.g.
+// "{accounts}:1" and "{accounts}:2", to obtain all keys atomically in a single
+// step. ObtainMulti requires a backend implementing Scripter. Hooks observe
+// the keys joined by commas. All locks share the same token. The Reentrant
+// and Fence options are ignored.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainMulti(keys []string, ttl time.Duration, opt *Options) (*MultiLock, error) {
+	if len(keys) == 0 {
+		return nil, errNoKeys
+	}
+	if err := c.checkServer(); err != nil {
+		return nil, err
+	}
+	if err := c.checkSpreading(); err != nil {
+		return nil, err
+	}
+
+	token, err := opt.getTokenGenerator(c.randomToken)()
+	if err != nil {
+		return nil, err
+	} else if token == "" {
+		return nil, errEmptyToken
+	}
+
+	name := strings.Join(keys, ",")
+	groups := c.slotGroups(keys)
+	value := encodeValue(token, opt.getMetadata(), opt.getMetadataMap())
+
+	var redisKeys []string
+	for _, group := range groups {
+		redisKeys = append(redisKeys, group...)
+	}
+
+	err = c.obtainLoop(name, redisKeys, ttl, opt, opt.getRetryStrategy(), time.Now().Add(opt.getObtainTimeout(ttl)), func() (bool, error) {
+		return c.obtainGroups(groups, value, ttl)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.newMultiLock(groups, value, ttl, opt), nil
+}
+
+// slotGroups returns the distinct redis keys of keys grouped by hash slot,
+// ordered by slot and key.
+func (c *Client) slotGroups(keys []string) [][]string {
+	redisKeys := make([]string, 0, len(keys))
+	seen := make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		key = c.redisKey(key)
+		if _, ok := seen[key]; !ok {
+			seen[key] = struct{}{}
+			redisKeys = append(redisKeys, key)
+		}
+	}
+
+	sort.Slice(redisKeys, func(i, j int) bool {
+		if si, sj := KeySlot(redisKeys[i]), KeySlot(redisKeys[j]); si != sj {
+			return si < sj
+		}
+		return redisKeys[i] < redisKeys[j]
+	})
+
+	var groups [][]string
+	for i, key := range redisKeys {
+		if i == 0 || KeySlot(key) != KeySlot(redisKeys[i-1]) {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], key)
+	}
+	return groups
+}
+
+// obtainGroups obtains all groups or none.
+func (c *Client) obtainGroups(groups [][]string, value string, ttl time.Duration) (bool, error) {
+	for i, group := range groups {
+		keys := group
+		if c.pauseKey != "" {
+			keys = append(keys[:len(keys):len(keys)], c.pauseKey)
+		}
+
+		res, err := c.runScript(ObtainMultiScript, keys, value, formatMillis(ttl), strconv.Itoa(len(group)))
+		if err == nil && res == int64(1) {
+			continue
+		}
+
+		// roll back, the keys expire in the worst case
+		for _, obtained := range groups[:i] {
+			for _, key := range obtained {
+				_ = c.release(key, value)
+			}
+		}
+
+		if err != nil {
+			return false, err
+		} else if res == int64(-1) {
+			return false, ErrLockingPaused
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+func (c *Client) newMultiLock(groups [][]string, value string, ttl time.Duration, opt *Options) *MultiLock {
+	now := time.Now()
+	m := new(MultiLock)
+	for _, group := range groups {
+		for _, key := range group {
+			lock := &Lock{
+				client:       c,
+				kind:         exclusiveLock,
+				key:          key,
+				value:        value,
+				maxRefreshes: opt.getMaxRefreshes(),
+				maxExtension: opt.getMaxExtension(),
+				obtainedAt:   now,
+				ttl:          ttl,
+				expiresAt:    now.Add(ttl),
+			}
+			c.track(lock)
+			if opt.getAutoRefresh() {
+				lock.startWatchdog(opt.getWatchdogContext(), ttl)
+			}
+			m.locks = append(m.locks, lock)
+		}
+	}
+	return m
+}
+
+// Locks returns the locks of the individual keys, in the order they were
+// obtained in.
+func (m *MultiLock) Locks() []*Lock {
+	return m.locks
+}
+
+// Token returns the token value shared by all locks.
+func (m *MultiLock) Token() string {
+	return m.locks[0].Token()
+}
+
+// Refresh extends all locks with a new TTL. It returns the first error, see
+// Lock.Refresh.
+func (m *MultiLock) Refresh(ttl time.Duration, opt *Options) error {
+	var err error
+	for _, lock := range m.locks {
+		if e := lock.Refresh(ttl, opt); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Release releases all locks. It returns the first error, see Lock.Release.
+func (m *MultiLock) Release() error {
+	var err error
+	for _, lock := range m.locks {
+		if e := lock.Release(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
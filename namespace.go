@@ -0,0 +1,106 @@
+package redislock
+
+import "time"
+
+// WithKeyPrefix stores all lock keys of the client under the given prefix,
+// e.g. "myapp:locks:", which keeps them apart from application keys. Locks
+// are still obtained with and report their key without the prefix.
+func WithKeyPrefix(prefix string) ClientOption {
+	return func(c *Client) {
+		c.keyPrefix = prefix
+	}
+}
+
+// LockInfo describes a lock found by Inspect.
+type LockInfo struct {
+	// Key is the key the lock was obtained with.
+	Key string
+	// RedisKey is the redis key of the lock.
+	RedisKey string
+	// Token is the token of the lock holder.
+	Token string
+	// Metadata is the metadata the lock was obtained with.
+	Metadata string
+	// MetadataMap is the metadata map the lock was obtained with.
+	MetadataMap map[string]string
+	// TTL is the remaining TTL of the lock, 0 if it does not expire.
+	TTL time.Duration
+}
+
+// Keys returns the keys within the namespace of the client matching the
+// glob-style pattern, e.g. "jobs:*". The keys are returned as passed to
+// Obtain, without the prefix of WithKeyPrefix.
+// May return ErrNotSupported if the backend does not implement Scanner.
+func (c *Client) Keys(match string) ([]string, error) {
+	scanner, ok := c.backend.(Scanner)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	redisKeys, err := scanner.Scan(c.scanPattern(match))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(redisKeys))
+	for _, redisKey := range redisKeys {
+		if c.reserved(redisKey) {
+			continue
+		}
+		if key, ok := c.logicalKey(redisKey); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// Inspect returns the holder of the exclusive lock key and its metadata, e.g.
+// to debug who holds it. Other kinds of locks cannot be inspected.
+// May return ErrLockNotHeld if the lock is not held or ErrNotSupported if the
+// backend does not implement Scanner.
+func (c *Client) Inspect(key string) (*LockInfo, error) {
+	scanner, ok := c.backend.(Scanner)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	redisKey := c.redisKey(key)
+	if c.reserved(redisKey) {
+		return nil, ErrLockNotHeld
+	}
+
+	value, err := scanner.Get(redisKey)
+	if err != nil {
+		return nil, err
+	} else if value == "" {
+		return nil, ErrLockNotHeld
+	}
+
+	ttl, err := c.backend.TTL(redisKey, value)
+	if err != nil {
+		return nil, err
+	}
+
+	v := decodeValue(value)
+	info := &LockInfo{Key: key, RedisKey: redisKey, Token: v.Token, Metadata: v.Metadata, MetadataMap: v.MetadataMap}
+	if ttl > 0 {
+		info.TTL = time.Duration(ttl) * time.Millisecond
+	}
+	return info, nil
+}
+
+// scanPattern returns the glob-style pattern of the redis keys within the
+// namespace of the client whose keys match the pattern match.
+func (c *Client) scanPattern(match string) string {
+	pattern := escapeGlob(c.keyPrefix)
+	if c.spreadSlots {
+		pattern += escapeGlob(c.spreadPrefix) + "{*}"
+	}
+	return pattern + match
+}
+
+// reserved reports whether the client uses redisKey for other purposes than
+// locks, i.e. for a fence counter or as control key of Pause.
+func (c *Client) reserved(redisKey string) bool {
+	return isFenceKey(redisKey) || redisKey == c.getPauseKey()
+}
@@ -0,0 +1,95 @@
+package redislock
+
+import "strings"
+
+// lua script for release notifications, run through the Scripter interface
+const LuaReleasePublishScript = `if redis.call("get", KEYS[1]) == ARGV[1] then redis.call("del", KEYS[1]) redis.call("publish", ARGV[2], KEYS[1]) return 1 else return 0 end`
+
+// ReleasePublishScript deletes KEYS[1] if it holds ARGV[1] and publishes the
+// key to channel ARGV[2]. Returns 1 if released, 0 otherwise.
+var ReleasePublishScript = &Script{Name: "release-publish", Source: LuaReleasePublishScript}
+
+// releaseChannelPrefix prefixes the key in the name of its release channel.
+const releaseChannelPrefix = "redislock:released:"
+
+// Subscriber is an optional interface of backends which support pub/sub. It
+// is required by WithReleaseNotifications to wait for releases.
+type Subscriber interface {
+	// PSubscribe subscribes to the glob-style channel patterns and returns
+	// once the subscriptions are active. The returned channel receives a
+	// value for messages on any of them, consecutive messages may be
+	// coalesced. The returned func unsubscribes.
+	PSubscribe(patterns ...string) (<-chan struct{}, func() error, error)
+}
+
+// WithReleaseNotifications enables the contention mode for busy keys. Releases
+// publish to a per-key channel and obtains which retry subscribe to it, so
+// they retry as soon as the lock is released rather than after the full
+// backoff of their RetryStrategy, which still bounds every wait. If keyspace
+// notifications are enabled on the server, waiters are also woken when locks
+// expire.
+//
+// Publishing requires a backend implementing Scripter and waiting one
+// implementing Subscriber, otherwise the client falls back to plain retries.
+// Only the releases of exclusive locks are published. Every waiting obtain
+// holds a subscription, which may be a dedicated connection.
+func WithReleaseNotifications() ClientOption {
+	return func(c *Client) {
+		c.notifyReleases = true
+	}
+}
+
+// release releases an exclusive lock, publishing the release if enabled.
+func (c *Client) release(key, value string) error {
+	if c.notifyReleases {
+		res, err := c.runScript(ReleasePublishScript, []string{key}, value, releaseChannelPrefix+key)
+		if err != ErrNotSupported {
+			if err == nil && res != int64(1) {
+				return ErrLockNotHeld
+			}
+			return err
+		}
+	}
+	return c.backend.Release(key, value)
+}
+
+// subscribeReleases subscribes to the release channels of keys and, if
+// enabled, to their keyspace notifications.
+// May return ErrNotSupported if the backend does not implement Subscriber.
+func (c *Client) subscribeReleases(keys ...string) (<-chan struct{}, func() error, error) {
+	subscriber, ok := c.backend.(Subscriber)
+	if !ok {
+		return nil, nil, ErrNotSupported
+	}
+
+	notifications := false
+	if profile, err := c.ServerProfile(); err == nil {
+		notifications = profile.KeyspaceNotifications
+	}
+
+	patterns := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		patterns = append(patterns, escapeGlob(releaseChannelPrefix+key))
+		if notifications {
+			patterns = append(patterns, "__keyspace@*__:"+escapeGlob(key))
+		}
+	}
+	return subscriber.PSubscribe(patterns...)
+}
+
+// escapeGlob escapes the special characters of glob-style patterns in s.
+func escapeGlob(s string) string {
+	if !strings.ContainsAny(s, `*?[]\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for _, ch := range s {
+		switch ch {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,38 @@
+package redislock
+
+import (
+	"errors"
+	"os"
+	"strconv"
+)
+
+var errEmptyToken = errors.New("redislock: empty token")
+
+// WithOwnerID sets the ID identifying the client as owner of reentrant locks,
+// see also ContextWithOwner.
+// Clients with the same owner ID share their reentrant locks, which allows a
+// process to resume them after a restart if the ID is stable, e.g. the name
+// of a pod in a StatefulSet. Owner IDs must be unique among the processes
+// obtaining the same keys.
+// Default: a random ID
+func WithOwnerID(id string) ClientOption {
+	return func(c *Client) {
+		c.owner = id
+	}
+}
+
+// NewOwnerID returns an owner ID of the form hostname:pid:random, which
+// identifies the process in lock tokens, e.g. when inspecting locks. Use it as
+// TokenGenerator or with WithOwnerID.
+func NewOwnerID() (string, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+
+	random, err := readToken(make([]byte, 16))
+	if err != nil {
+		return "", err
+	}
+	return hostname + ":" + strconv.Itoa(os.Getpid()) + ":" + random, nil
+}
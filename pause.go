@@ -0,0 +1,84 @@
+package redislock
+
+import "time"
+
+// DefaultPauseKey is the suggested control key for WithPauseKey. Clients with
+// a prefix of WithKeyPrefix use the prefix followed by "__paused" instead.
+const DefaultPauseKey = "redislock:__paused"
+
+// lua scripts for pausing, run through the Scripter interface
+const (
+	LuaObtainScript = `if redis.call("exists", KEYS[2]) == 1 then return -1 end if redis.call("set", KEYS[1], ARGV[1], "PX", ARGV[2], "NX") then return 1 else return 0 end`
+	LuaPauseScript  = `if tonumber(ARGV[1]) > 0 then redis.call("set", KEYS[1], "1", "PX", ARGV[1]) else redis.call("set", KEYS[1], "1") end return 1`
+	LuaResumeScript = `return redis.call("del", KEYS[1])`
+	LuaPausedScript = `return redis.call("exists", KEYS[1])`
+)
+
+var (
+	// ObtainScript sets KEYS[1] to ARGV[1] with a TTL of ARGV[2] milliseconds
+	// unless it exists. Returns 1 if set, 0 if not and -1 if the pause key
+	// KEYS[2] exists.
+	ObtainScript = &Script{Name: "obtain", Source: LuaObtainScript}
+
+	// PauseScript sets the pause key KEYS[1] with a TTL of ARGV[1]
+	// milliseconds, or without expiry if ARGV[1] is 0. Returns 1.
+	PauseScript = &Script{Name: "pause", Source: LuaPauseScript}
+
+	// ResumeScript deletes the pause key KEYS[1]. Returns the number of
+	// deleted keys.
+	ResumeScript = &Script{Name: "resume", Source: LuaResumeScript}
+
+	// PausedScript returns 1 if the pause key KEYS[1] exists, 0 otherwise.
+	PausedScript = &Script{Name: "paused", Source: LuaPausedScript}
+)
+
+// WithPauseKey enables the maintenance kill-switch. While the control key
+// exists, e.g. after Pause or a manual SET by operators, all new obtains fail
+// fast with ErrLockingPaused, while existing locks can still be refreshed and
+// released. The key is checked atomically by the obtain script, which
+// requires a backend implementing Scripter. On redis cluster the control key
+// must hash to the same slot as the lock keys. Pass DefaultPauseKey to use the
+// control key of the namespace of the client.
+func WithPauseKey(key string) ClientOption {
+	return func(c *Client) {
+		c.pauseKey = key
+	}
+}
+
+// Pause sets the control key configured through WithPauseKey, or the default
+// control key of the namespace, for the given TTL or until Resume if ttl is 0.
+func (c *Client) Pause(ttl time.Duration) error {
+	_, err := c.runScript(PauseScript, []string{c.getPauseKey()}, formatMillis(ttl))
+	return err
+}
+
+// Resume deletes the control key configured through WithPauseKey.
+func (c *Client) Resume() error {
+	_, err := c.runScript(ResumeScript, []string{c.getPauseKey()})
+	return err
+}
+
+// Paused reports whether the control key configured through WithPauseKey
+// exists.
+func (c *Client) Paused() (bool, error) {
+	res, err := c.runScript(PausedScript, []string{c.getPauseKey()})
+	if err != nil {
+		return false, err
+	}
+	return res == int64(1), nil
+}
+
+func (c *Client) getPauseKey() string {
+	if c.pauseKey != "" {
+		return c.pauseKey
+	}
+	return c.defaultPauseKey()
+}
+
+// defaultPauseKey returns the control key of the namespace of the client.
+func (c *Client) defaultPauseKey() string {
+	if c.keyPrefix != "" {
+		return c.keyPrefix + "__paused"
+	}
+	return DefaultPauseKey
+}
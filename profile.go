@@ -0,0 +1,145 @@
+package redislock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// profileRetryInterval is the minimum interval between detections of the
+// ServerProfile after an error.
+const profileRetryInterval = time.Second
+
+// ServerInspector is an optional interface of backends which can report the
+// version and configuration of the redis server. It is used to detect the
+// ServerProfile.
+type ServerInspector interface {
+	// Info returns the output of the INFO command for section.
+	Info(section string) (string, error)
+
+	// ConfigGet returns the value of a configuration parameter or an empty
+	// string if it is unknown.
+	ConfigGet(parameter string) (string, error)
+}
+
+// ServerProfile describes the version and capabilities of the redis server.
+//
+// Only the version and keyspace notifications are detected. All locks are
+// maintained with SET and lua scripts, which work the same on any server
+// since 2.6.12, so newer commands such as GETDEL, SET with GET, KEEPTTL or
+// functions are neither detected nor used.
+type ServerProfile struct {
+	// Version is the redis version, empty if unknown. Obtains fail with
+	// ErrUnsupportedServer if it is known to be older than 2.6.12.
+	Version string
+
+	// KeyspaceNotifications reports whether keyspace notifications are
+	// enabled through notify-keyspace-events. If so, the waiters of
+	// WithReleaseNotifications are also woken when locks expire.
+	KeyspaceNotifications bool
+}
+
+// String returns a summary of the profile for logging.
+func (p ServerProfile) String() string {
+	version := p.Version
+	if version == "" {
+		version = "unknown"
+	}
+
+	var caps []string
+	if p.KeyspaceNotifications {
+		caps = append(caps, "notifications")
+	}
+	return fmt.Sprintf("redis %s [%s]", version, strings.Join(caps, " "))
+}
+
+// olderThan reports whether the version is known to be older than
+// major.minor.patch. Versions which cannot be parsed, e.g. "7.0" or those with
+// a vendor suffix, never are.
+func (p ServerProfile) olderThan(major, minor, patch int) bool {
+	parts := strings.Split(p.Version, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	var version [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return false
+		}
+		version[i] = n
+	}
+
+	want := [3]int{major, minor, patch}
+	for i := range version {
+		if version[i] != want[i] {
+			return version[i] < want[i]
+		}
+	}
+	return false
+}
+
+// WithServerDetection detects the ServerProfile when the client is created
+// instead of on first use.
+func WithServerDetection() ClientOption {
+	return func(c *Client) {
+		c.detectEagerly = true
+	}
+}
+
+// ServerProfile returns the profile of the redis server, which is detected
+// on first use. Failed detections are retried on later calls, at most once
+// per second. The profile is empty if the backend does not implement
+// ServerInspector.
+func (c *Client) ServerProfile() (ServerProfile, error) {
+	c.profileMu.Lock()
+	defer c.profileMu.Unlock()
+
+	if c.profileAt.IsZero() || (c.profileErr != nil && time.Since(c.profileAt) >= profileRetryInterval) {
+		c.profile, c.profileErr = detectProfile(c.backend)
+		c.profileAt = time.Now()
+		c.debugf(DebugInfo, "detected server profile=%q err=%v", c.profile, c.profileErr)
+	}
+	return c.profile, c.profileErr
+}
+
+// checkServer returns ErrUnsupportedServer if the server is known to lack the
+// commands required for locking, i.e. SET with NX and PX and lua scripting.
+// Detection errors and unparsable versions are not fatal to locking.
+func (c *Client) checkServer() error {
+	if p, err := c.ServerProfile(); err == nil && p.olderThan(2, 6, 12) {
+		return ErrUnsupportedServer
+	}
+	return nil
+}
+
+func detectProfile(backend Backend) (ServerProfile, error) {
+	inspector, ok := backend.(ServerInspector)
+	if !ok {
+		return ServerProfile{}, nil
+	}
+
+	info, err := inspector.Info("server")
+	if err == ErrNotSupported {
+		// forwarded to a backend which cannot be inspected, see FallbackClient
+		return ServerProfile{}, nil
+	} else if err != nil {
+		return ServerProfile{}, err
+	}
+
+	var p ServerProfile
+	for _, line := range strings.Split(info, "\n") {
+		if v := strings.TrimPrefix(line, "redis_version:"); v != line {
+			p.Version = strings.TrimSpace(v)
+			break
+		}
+	}
+
+	// CONFIG is often disabled on managed servers, assume no notifications
+	if events, err := inspector.ConfigGet("notify-keyspace-events"); err == nil {
+		p.KeyspaceNotifications = strings.ContainsAny(events, "KE")
+	}
+	return p, nil
+}
@@ -0,0 +1,178 @@
+package redislock
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// driftFactor and driftMin estimate the clock drift between the redis nodes
+// of a QuorumClient, as suggested by the Redlock algorithm.
+const (
+	driftFactor = 0.01
+	driftMin    = 2 * time.Millisecond
+)
+
+// QuorumClient is a Backend which implements the Redlock algorithm on top of
+// independent redis masters. A lock is held when it was stored on a majority
+// of nodes within its TTL, reduced by the time it took to store it and the
+// estimated clock drift between the nodes.
+//
+// See https://redis.io/topics/distlock for the safety and liveness guarantees.
+type QuorumClient struct {
+	nodes  []Backend
+	quorum int
+}
+
+// NewQuorum creates a new QuorumClient for the given nodes.
+func NewQuorum(nodes ...Backend) *QuorumClient {
+	return &QuorumClient{nodes: nodes, quorum: len(nodes)/2 + 1}
+}
+
+// NewMulti creates a new Client which obtains, refreshes and releases locks on
+// a majority of independent redis masters, see QuorumClient.
+func NewMulti(clients ...Backend) *Client {
+	return New(NewQuorum(clients...))
+}
+
+func (c *QuorumClient) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	start := time.Now()
+	ok, failed, err := c.each(func(_ int, node Backend) (bool, error) {
+		return node.SetNX(key, value, ttl)
+	})
+	if ok >= c.quorum && validity(ttl, time.Since(start)) > 0 {
+		return true, nil
+	}
+
+	// undo partial obtains, the nodes expire them in the worst case
+	c.each(func(_ int, node Backend) (bool, error) {
+		return true, node.Release(key, value)
+	})
+
+	// unreachable nodes, rather than contention, prevented the quorum
+	if failed > len(c.nodes)-c.quorum {
+		return false, err
+	}
+	return false, nil
+}
+
+func (c *QuorumClient) Refresh(key, value string, ttl string) error {
+	ms, err := strconv.ParseInt(ttl, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	ok, failed, err := c.each(func(_ int, node Backend) (bool, error) {
+		if err := node.Refresh(key, value, ttl); err == ErrNotObtained {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if ok >= c.quorum && validity(time.Duration(ms)*time.Millisecond, time.Since(start)) > 0 {
+		return nil
+	} else if failed > len(c.nodes)-c.quorum {
+		return err
+	}
+	return ErrNotObtained
+}
+
+// Release releases the lock on all nodes. It succeeds if the lock was held on
+// a majority of nodes.
+func (c *QuorumClient) Release(key, value string) error {
+	ok, failed, err := c.each(func(_ int, node Backend) (bool, error) {
+		if err := node.Release(key, value); err == ErrLockNotHeld {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		return true, nil
+	})
+	if ok >= c.quorum {
+		return nil
+	} else if failed > len(c.nodes)-c.quorum {
+		return err
+	}
+	return ErrLockNotHeld
+}
+
+// TTL returns the time until the lock is held by less than a majority of
+// nodes, reduced by the estimated clock drift.
+func (c *QuorumClient) TTL(key, value string) (int64, error) {
+	res := make([]int64, len(c.nodes))
+	ok, failed, err := c.each(func(i int, node Backend) (bool, error) {
+		ttl, err := node.TTL(key, value)
+		if err == ErrLockNotHeld {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		res[i] = ttl
+		return ttl > 0 || ttl == -1, nil
+	})
+	if ok < c.quorum {
+		if failed > len(c.nodes)-c.quorum {
+			return 0, err
+		}
+		return 0, ErrLockNotHeld
+	}
+
+	// order by remaining TTL, descending, with locks without expiry first
+	ttls := res[:0]
+	for _, ttl := range res {
+		if ttl > 0 || ttl == -1 {
+			ttls = append(ttls, ttl)
+		}
+	}
+	sort.Slice(ttls, func(i, j int) bool {
+		return ttls[j] != -1 && (ttls[i] == -1 || ttls[i] > ttls[j])
+	})
+	ttl := ttls[c.quorum-1]
+	if ttl == -1 {
+		return -1, nil
+	}
+
+	if ms := validity(time.Duration(ttl)*time.Millisecond, 0).Milliseconds(); ms > 0 {
+		return ms, nil
+	}
+	return 0, ErrLockNotHeld
+}
+
+// each calls fn for every node concurrently and returns the number of nodes
+// fn succeeded and failed on, as well as the first error.
+func (c *QuorumClient) each(fn func(i int, node Backend) (bool, error)) (ok, failed int, err error) {
+	oks := make([]bool, len(c.nodes))
+	errs := make([]error, len(c.nodes))
+
+	var wg sync.WaitGroup
+	for i, node := range c.nodes {
+		wg.Add(1)
+		go func(i int, node Backend) {
+			defer wg.Done()
+			oks[i], errs[i] = fn(i, node)
+		}(i, node)
+	}
+	wg.Wait()
+
+	for i := range c.nodes {
+		if errs[i] != nil {
+			failed++
+			if err == nil {
+				err = errs[i]
+			}
+		} else if oks[i] {
+			ok++
+		}
+	}
+	return ok, failed, err
+}
+
+// validity returns the remaining validity of a lock with the given TTL, set
+// elapsed ago, compensated for clock drift.
+func validity(ttl, elapsed time.Duration) time.Duration {
+	drift := time.Duration(float64(ttl)*driftFactor) + driftMin
+	return ttl - elapsed - drift
+}
@@ -6,12 +6,15 @@ import (
 	"encoding/base64"
 	"errors"
 	"io"
-	"strconv"
+	"log"
+	mrand "math/rand"
+	"net"
+	"strings"
 	"sync"
 	"time"
 )
 
-//lua scripts which should be loaded to redis client when implementing RedisClient interface
+//lua scripts which should be loaded to redis client when implementing Backend interface
 const (
 	LuaRefreshScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
 	LuaReleaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
@@ -24,54 +27,265 @@ var (
 
 	// ErrLockNotHeld is returned when trying to release an inactive lock.
 	ErrLockNotHeld = errors.New("redislock: lock not held")
+
+	// ErrRefreshLimit is returned when a refresh would exceed the
+	// MaxRefreshes or MaxExtension policy the lock was obtained with.
+	ErrRefreshLimit = errors.New("redislock: refresh limit exceeded")
+
+	// ErrNotSupported is returned when the backend does not implement an
+	// optional interface required by the operation.
+	ErrNotSupported = errors.New("redislock: not supported by backend")
+
+	// ErrDraining is returned when trying to obtain a lock from a draining
+	// client.
+	ErrDraining = errors.New("redislock: client is draining")
+
+	// ErrUnsupportedServer is returned when the redis server lacks the
+	// commands required for locking.
+	ErrUnsupportedServer = errors.New("redislock: unsupported redis server")
+
+	// ErrLockingPaused is returned when trying to obtain a lock while the
+	// control key of WithPauseKey is set.
+	ErrLockingPaused = errors.New("redislock: locking paused")
+
+	// ErrLockLost is returned by Do when the lock was lost while the
+	// callback was running, joined with the error of the callback, if any.
+	ErrLockLost = errors.New("redislock: lock lost")
 )
 
-//Implement the interface with which every redis client you wish to use
-type RedisClient interface {
+// Backend abstracts the store locks are kept in. Redis clients implement it
+// using the lua scripts above, other coordination services such as etcd,
+// Consul or ZooKeeper can implement it with their own primitives, e.g. leases,
+// sessions or ephemeral nodes. Every method must be atomic and safe for
+// concurrent use. See locktest.CheckBackend to verify an implementation.
+type Backend interface {
+	// SetNX stores value under key with the given TTL unless key exists and
+	// reports whether it was stored.
 	SetNX(key, value string, ttl time.Duration) (bool, error)
+
+	// Refresh sets the TTL of key to ttl milliseconds, formatted as decimal
+	// string, if key holds value. Otherwise it returns ErrNotObtained.
 	Refresh(key, value string, ttl string) error
+
+	// Release deletes key if it holds value. Otherwise it returns
+	// ErrLockNotHeld.
 	Release(key, value string) error
+
+	// TTL returns the remaining TTL of key in milliseconds if key holds
+	// value and -1 if key holds value without expiry. Otherwise it returns
+	// ErrLockNotHeld.
 	TTL(key, value string) (int64, error)
 }
 
+// RedisClient is the original name of Backend, kept for compatibility.
+type RedisClient = Backend
+
 type Client struct {
-	redisClient RedisClient
-	tmp         []byte
-	tmpMu       sync.Mutex
+	backend Backend
+	tmp     []byte
+	tmpMu   sync.Mutex
+
+	heldMu      sync.Mutex
+	held        map[*Lock]struct{}
+	heldChanged chan struct{}
+	heldPruneAt int
+	draining    int32
+
+	reportInterval time.Duration
+	reportFunc     func([]HeldLock)
+
+	keyPrefix    string
+	spreadSlots  bool
+	spreadPrefix string
+
+	debugLog   *log.Logger
+	debugLevel DebugLevel
+	hooks      Hooks
+
+	pauseKey       string
+	notifyReleases bool
+
+	ownerOnce sync.Once
+	owner     string
+	ownerErr  error
+
+	detectEagerly bool
+	profileMu     sync.Mutex
+	profileAt     time.Time
+	profile       ServerProfile
+	profileErr    error
+
+	closeOnce sync.Once
+	closed    chan struct{}
 }
 
-// // New creates a new Client instance with a custom namespace.
-func New(redisClient RedisClient) *Client {
-	return &Client{redisClient: redisClient}
+// ClientOption configures optional behaviour of a Client.
+type ClientOption func(*Client)
+
+// New creates a new Client instance. Use WithKeyPrefix to keep its locks in a
+// custom namespace.
+func New(backend Backend, opts ...ClientOption) *Client {
+	c := &Client{
+		backend:     backend,
+		held:        make(map[*Lock]struct{}),
+		heldChanged: make(chan struct{}),
+		closed:      make(chan struct{}),
+		hooks:       NoopHooks{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.pauseKey == DefaultPauseKey {
+		c.pauseKey = c.defaultPauseKey()
+	}
+
+	if c.detectEagerly {
+		_, _ = c.ServerProfile()
+	}
+
+	if c.reportFunc != nil && c.reportInterval > 0 {
+		go c.report()
+	}
+	return c
+}
+
+// Close stops any background work started by the client, including auto
+// refreshes. Locks held by the client are not released.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
 }
 
-// Obtain tries to obtain a new lock using a key with the given TTL.
+// Obtain tries to obtain a new lock using a key with the given TTL, retrying
+// according to the RetryStrategy until the ObtainTimeout of the options passes.
 // May return ErrNotObtained if not successful.
 func (c *Client) Obtain(key string, ttl time.Duration, opt *Options) (*Lock, error) {
-	// Create a random token
-	token, err := c.randomToken()
+	return c.obtainLock(opt.getKind(), 0, key, ttl, opt, opt.getRetryStrategy(), time.Now().Add(opt.getObtainTimeout(ttl)))
+}
+
+// ObtainBlocking tries to obtain a new lock using a key with the given TTL,
+// retrying indefinitely with a jittered backoff until the lock is obtained or
+// the context of the options is cancelled. Without a context it blocks until
+// the lock is obtained. Network errors and timeouts, e.g. a reset connection,
+// are retried as well, all other errors are returned. The RetryStrategy and
+// ObtainTimeout of the options have no effect.
+// May return ctx.Err(), ErrDraining, ErrNotSupported, ErrUnsupportedServer,
+// ErrLockingPaused or errors replied by redis.
+func (c *Client) ObtainBlocking(key string, ttl time.Duration, opt *Options) (*Lock, error) {
+	return c.obtainLock(opt.getKind(), 0, key, ttl, opt, blockingBackoff(), time.Time{})
+}
+
+// obtainLock obtains a lock of the given kind through obtainLoop. The limit of
+// holders only applies to semaphores.
+func (c *Client) obtainLock(kind lockKind, limit int, key string, ttl time.Duration, opt *Options, retry RetryStrategy, deadline time.Time) (*Lock, error) {
+	if err := c.checkServer(); err != nil {
+		return nil, err
+	}
+	if err := c.checkSpreading(); err != nil {
+		return nil, err
+	}
+
+	// Create a random token, reentrant locks are identified by their owner
+	newToken := opt.getTokenGenerator(c.randomToken)
+	if kind == reentrantLock {
+		newToken = func() (string, error) { return c.reentrantOwner(opt.getContext()) }
+	}
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	} else if token == "" {
+		return nil, errEmptyToken
+	}
+
+	name, key := key, c.redisKey(key)
+	value := encodeValue(token, opt.getMetadata(), opt.getMetadataMap())
+
+	var fence int64
+	err = c.obtainLoop(name, []string{key}, ttl, opt, retry, deadline, func() (ok bool, err error) {
+		ok, fence, err = c.obtain(kind, limit, key, value, ttl)
+		return ok, err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	value := token + opt.getMetadata()
+	now := time.Now()
+	lock := &Lock{
+		client:       c,
+		kind:         kind,
+		key:          key,
+		value:        value,
+		fence:        fence,
+		maxRefreshes: opt.getMaxRefreshes(),
+		maxExtension: opt.getMaxExtension(),
+		obtainedAt:   now,
+		ttl:          ttl,
+		expiresAt:    now.Add(ttl),
+	}
+	c.track(lock)
+	if opt.getAutoRefresh() {
+		lock.startWatchdog(opt.getWatchdogContext(), ttl)
+	}
+	return lock, nil
+}
+
+// obtainLoop calls attempt until it succeeds or the deadline passes, waking up
+// early on releases of the redis keys. A zero deadline retries for as long as
+// the retry strategy allows, including after network errors. Hooks observe
+// name.
+func (c *Client) obtainLoop(name string, keys []string, ttl time.Duration, opt *Options, retry RetryStrategy, deadline time.Time, attempt func() (bool, error)) error {
+	key := strings.Join(keys, ",")
 	ctx := opt.getContext()
-	retry := opt.getRetryStrategy()
+
+	start := time.Now()
+	attempts := 0
+	subscribed := false
 
 	var timer *time.Timer
-	for deadline := time.Now().Add(ttl); time.Now().Before(deadline); {
+	var released <-chan struct{}
+	for deadline.IsZero() || time.Now().Before(deadline) {
+		if c.Draining() {
+			c.hooks.OnObtainFailed(name, attempts, time.Since(start), ErrDraining)
+			return ErrDraining
+		}
+
+		attempts++
+		c.debugf(DebugVerbose, "obtain attempt key=%s attempt=%d", key, attempts)
+		c.hooks.OnObtainAttempt(name, attempts)
 
-		ok, err := c.obtain(key, value, ttl)
-		if err != nil {
-			return nil, err
+		ok, err := attempt()
+		if err != nil && !(deadline.IsZero() && transient(err)) {
+			c.debugf(DebugInfo, "obtain failed key=%s attempts=%d err=%v", key, attempts, err)
+			c.hooks.OnObtainFailed(name, attempts, time.Since(start), err)
+			return err
 		} else if ok {
-			return &Lock{client: c, key: key, value: value}, nil
+			c.debugf(DebugInfo, "obtained key=%s ttl=%s attempts=%d wait=%s", key, ttl, attempts, time.Since(start))
+			c.hooks.OnObtain(name, attempts, time.Since(start))
+			return nil
 		}
 
-		backoff := retry.NextBackoff()
+		backoff := nextBackoff(retry, RetryAttempt{Attempt: attempts, Elapsed: time.Since(start)})
 		if backoff < 1 {
 			break
+		} else if err != nil {
+			c.debugf(DebugInfo, "obtain failed key=%s attempt=%d backoff=%s err=%v", key, attempts, backoff, err)
+		} else {
+			c.hooks.OnContention(name, attempts, backoff)
+		}
+
+		// subscribe once, then retry right away as the lock may have been
+		// released in the meantime
+		if c.notifyReleases && !subscribed {
+			subscribed = true
+			ch, unsubscribe, err := c.subscribeReleases(keys...)
+			if err == nil {
+				defer unsubscribe()
+				released = ch
+				continue
+			}
+			c.debugf(DebugVerbose, "subscribe failed key=%s err=%v", key, err)
 		}
+		c.debugf(DebugVerbose, "obtain backoff key=%s attempt=%d backoff=%s", key, attempts, backoff)
 
 		if timer == nil {
 			timer = time.NewTimer(backoff)
@@ -82,16 +296,63 @@ func (c *Client) Obtain(key string, ttl time.Duration, opt *Options) (*Lock, err
 
 		select {
 		case <-ctx.Done():
-			return nil, ctx.Err()
+			c.debugf(DebugInfo, "obtain cancelled key=%s attempts=%d err=%v", key, attempts, ctx.Err())
+			c.hooks.OnObtainFailed(name, attempts, time.Since(start), ctx.Err())
+			return ctx.Err()
 		case <-timer.C:
+		case <-released:
 		}
 	}
 
-	return nil, ErrNotObtained
+	c.debugf(DebugInfo, "not obtained key=%s attempts=%d wait=%s", key, attempts, time.Since(start))
+	c.hooks.OnObtainFailed(name, attempts, time.Since(start), ErrNotObtained)
+	return ErrNotObtained
 }
 
-func (c *Client) obtain(key, value string, ttl time.Duration) (bool, error) {
-	return c.redisClient.SetNX(key, value, ttl)
+// obtain tries to obtain the lock once and returns the fence token of fenced
+// locks.
+func (c *Client) obtain(kind lockKind, limit int, key, value string, ttl time.Duration) (bool, int64, error) {
+	var ok bool
+	var err error
+	switch kind {
+	case reentrantLock:
+		ok, err = c.obtainReentrant(key, decodeValue(value).Token, formatMillis(ttl))
+	case readLock, writeLock:
+		ok, err = c.obtainRW(kind, key, value, formatMillis(ttl))
+	case semaphoreLock:
+		ok, err = c.obtainSemaphore(key, value, formatMillis(ttl), limit)
+	case fencedLock:
+		fence, err := c.obtainFenced(key, value, formatMillis(ttl))
+		return fence > 0, fence, err
+	default:
+		ok, err = c.obtainExclusive(key, value, ttl)
+	}
+	return ok, 0, err
+}
+
+// transient reports whether err may resolve itself on retry, i.e. it is a
+// network error or a timeout. Errors replied by redis, e.g. WRONGTYPE, and
+// errors of the configuration of the client never do.
+func transient(err error) bool {
+	if err == ErrNotObtained || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+func (c *Client) obtainExclusive(key, value string, ttl time.Duration) (bool, error) {
+	if c.pauseKey == "" {
+		return c.backend.SetNX(key, value, ttl)
+	}
+
+	res, err := c.runScript(ObtainScript, []string{key, c.pauseKey}, value, formatMillis(ttl))
+	if err != nil {
+		return false, err
+	} else if res == int64(-1) {
+		return false, ErrLockingPaused
+	}
+	return res == int64(1), nil
 }
 
 func (c *Client) randomToken() (string, error) {
@@ -101,45 +362,97 @@ func (c *Client) randomToken() (string, error) {
 	if len(c.tmp) == 0 {
 		c.tmp = make([]byte, 16)
 	}
+	return readToken(c.tmp)
+}
 
-	if _, err := io.ReadFull(rand.Reader, c.tmp); err != nil {
+// readToken fills buf with random bytes and returns them encoded as token.
+func readToken(buf []byte) (string, error) {
+	if _, err := io.ReadFull(rand.Reader, buf); err != nil {
 		return "", err
 	}
-	return base64.RawURLEncoding.EncodeToString(c.tmp), nil
+	return base64.RawURLEncoding.EncodeToString(buf), nil
 }
 
 // --------------------------------------------------------------------
 
+// lockKind selects the scripts a lock is maintained with.
+type lockKind int
+
+const (
+	exclusiveLock lockKind = iota
+	reentrantLock
+	readLock
+	writeLock
+	fencedLock
+	semaphoreLock
+)
+
 type Lock struct {
 	client *Client
+	kind   lockKind
 	key    string
 	value  string
+	fence  int64
+
+	maxRefreshes int
+	maxExtension time.Duration
+	obtainedAt   time.Time
+	ttl          time.Duration
+
+	mu        sync.Mutex
+	refreshes int
+	expiresAt time.Time // zero if the key does not expire
+	released  bool
+
+	watchdog *watchdog
+	stopOnce sync.Once
 }
 
 // Obtain is a short-cut for New(...).Obtain(...).
-func Obtain(redisClient RedisClient, key string, ttl time.Duration, opt *Options) (*Lock, error) {
-	return New(redisClient).Obtain(key, ttl, opt)
+func Obtain(backend Backend, key string, ttl time.Duration, opt *Options) (*Lock, error) {
+	return New(backend).Obtain(key, ttl, opt)
 }
 
-// Key returns the redis key used by the lock.
+// Key returns the key the lock was obtained with.
 func (l *Lock) Key() string {
+	key, _ := l.client.logicalKey(l.key)
+	return key
+}
+
+// RedisKey returns the redis key used by the lock, i.e. the key including the
+// prefix of WithKeyPrefix and the hash tag of WithSlotSpreading.
+func (l *Lock) RedisKey() string {
 	return l.key
 }
 
 // Token returns the token value set by the lock.
 func (l *Lock) Token() string {
-	return l.value[:22]
+	return decodeValue(l.value).Token
 }
 
 // Metadata returns the metadata of the lock.
 func (l *Lock) Metadata() string {
-	return l.value[22:]
+	return decodeValue(l.value).Metadata
 }
 
+// TTL returns the remaining TTL of the lock, 0 if it is held without expiry.
+// May return ErrLockNotHeld if the lock is no longer held.
 func (l *Lock) TTL() (time.Duration, error) {
-	res, err := l.client.redisClient.TTL(l.key, l.value)
+	var res int64
+	var err error
+	switch l.kind {
+	case reentrantLock:
+		res, err = l.ttlReentrant()
+	case readLock, writeLock, semaphoreLock:
+		res, err = l.ttlRW()
+	default:
+		res, err = l.client.backend.TTL(l.key, l.value)
+	}
 	if err != nil {
 		return 0, err
+	} else if res == -3 {
+		// returned by backends implementing the original contract
+		return 0, ErrLockNotHeld
 	}
 
 	if res > 0 {
@@ -149,16 +462,83 @@ func (l *Lock) TTL() (time.Duration, error) {
 	return 0, nil
 }
 
+// IsHeld reports whether the lock is still held. The Backend interface does
+// not support cancellation, ctx is only checked before querying.
+func (l *Lock) IsHeld(ctx context.Context) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	if _, err := l.TTL(); err == ErrLockNotHeld {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Refresh extends the lock with a new TTL.
-// May return ErrNotObtained if refresh is unsuccessful.
+// May return ErrNotObtained if refresh is unsuccessful or ErrRefreshLimit
+// if the refresh policy of the lock has been exhausted.
 func (l *Lock) Refresh(ttl time.Duration, opt *Options) error {
-	return l.client.redisClient.Refresh(l.key, l.value, strconv.FormatInt(int64(ttl/time.Millisecond), 10))
+	err := l.refresh(ttl)
+	l.client.debugf(DebugInfo, "refresh key=%s ttl=%s err=%v", l.key, ttl, err)
+	l.client.hooks.OnRefresh(l.Key(), ttl, err)
+	return err
+}
+
+func (l *Lock) refresh(ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.maxRefreshes > 0 && l.refreshes >= l.maxRefreshes {
+		return ErrRefreshLimit
+	}
+	// refreshes set the expiry rather than adding to it, so measure against
+	// the original deadline
+	now := time.Now()
+	if l.maxExtension > 0 && now.Add(ttl).After(l.obtainedAt.Add(l.ttl+l.maxExtension)) {
+		return ErrRefreshLimit
+	}
+
+	if err := l.refreshBackend(formatMillis(ttl)); err != nil {
+		return err
+	}
+	l.refreshes++
+	l.expiresAt = now.Add(ttl)
+	return nil
 }
 
-// Release manually releases the lock.
+func (l *Lock) refreshBackend(ttl string) error {
+	switch l.kind {
+	case reentrantLock:
+		return l.refreshReentrant(ttl)
+	case readLock, writeLock, semaphoreLock:
+		return l.refreshRW(ttl)
+	}
+	return l.client.backend.Refresh(l.key, l.value, ttl)
+}
+
+// Release manually releases the lock and stops auto refreshing it.
 // May return ErrLockNotHeld.
 func (l *Lock) Release() error {
-	return l.client.redisClient.Release(l.key, l.value)
+	l.stopWatchdog()
+
+	var err error
+	switch l.kind {
+	case reentrantLock:
+		err = l.releaseReentrant()
+	case readLock, writeLock, semaphoreLock:
+		err = l.releaseRW()
+	default:
+		err = l.client.release(l.key, l.value)
+	}
+	l.client.debugf(DebugInfo, "release key=%s err=%v", l.key, err)
+	l.client.hooks.OnRelease(l.Key(), time.Since(l.obtainedAt), err)
+	if err == nil || err == ErrLockNotHeld {
+		l.client.untrack(l)
+	}
+	return err
 }
 
 // --------------------------------------------------------------------
@@ -169,11 +549,79 @@ type Options struct {
 	// Default: do not retry
 	RetryStrategy RetryStrategy
 
-	// Metadata string is appended to the lock token.
+	// Metadata string is stored along with the lock token.
 	Metadata string
 
-	// Optional context for Obtain timeout and cancellation control.
+	// MetadataMap is stored along with the lock token, e.g. to record the
+	// host or job holding the lock. See Lock.MetadataValue and Inspect.
+	MetadataMap map[string]string
+
+	// Optional context for Obtain timeout and cancellation control. It does
+	// not stop auto refreshes of the obtained lock, see WatchdogContext.
 	Context context.Context
+
+	// ObtainTimeout limits the time Obtain spends retrying, independent of
+	// the TTL of the lock. It has no effect on ObtainBlocking.
+	// Default: the TTL of the lock
+	ObtainTimeout time.Duration
+
+	// MaxRefreshes limits how many times the obtained lock may be refreshed.
+	// Default: 0, unlimited
+	MaxRefreshes int
+
+	// MaxExtension limits the duration by which the obtained lock may be
+	// extended beyond its original TTL through refreshes. A refresh fails
+	// with ErrRefreshLimit if the new expiry would be later than the time
+	// the lock was obtained plus its TTL plus MaxExtension.
+	// Default: 0, unlimited
+	MaxExtension time.Duration
+
+	// AutoRefresh refreshes the obtained lock with its TTL in the background
+	// every third of the TTL until it is released, the WatchdogContext is
+	// cancelled or the client is closed. Use Lock.Lost to learn when a
+	// refresh fails.
+	// Refreshes count towards MaxRefreshes and MaxExtension.
+	// Default: false
+	AutoRefresh bool
+
+	// WatchdogContext stops the auto refreshes of the obtained lock when
+	// cancelled, which leaves the lock to expire with its TTL. It is separate
+	// from Context, so that an obtain timeout does not stop refreshing.
+	// Default: none, refresh until released
+	WatchdogContext context.Context
+
+	// Reentrant obtains a lock which its owner may obtain again while holding
+	// it, e.g. in recursive code paths. Every obtain returns a Lock holding
+	// the key once and the key is only deleted when all of them have been
+	// released.
+	//
+	// The owner is the Client unless Context carries an owner set through
+	// ContextWithOwner. Without it, all goroutines sharing the Client re-enter
+	// each other's locks and are not excluded from one another. Pass the
+	// context along to recursive calls instead.
+	//
+	// Reentrant locks are identified by their owner, not by a random token,
+	// and are stored as redis hash, which requires a backend implementing
+	// Scripter. Do not mix them with other locks on the same key.
+	// Default: false
+	Reentrant bool
+
+	// Fence obtains a lock with a fencing token, see Lock.FenceToken. The
+	// tokens are generated by a per-key counter, incremented atomically with
+	// every obtain, which requires a backend implementing Scripter. The
+	// counter is stored under Client.FenceKey(key) without expiry, so that
+	// tokens keep increasing after the lock expired. Ignored by reentrant and
+	// read/write locks.
+	// Default: false
+	Fence bool
+
+	// TokenGenerator generates the token of the lock, e.g. to obtain locks
+	// with deterministic tokens in tests or with a stable token which allows
+	// to resume locks after a restart through LockFromToken. Tokens must be
+	// unique among the holders of a key. Reentrant locks use the owner ID of
+	// the client instead, see WithOwnerID.
+	// Default: a random token
+	TokenGenerator func() (string, error)
 }
 
 func (o *Options) getMetadata() string {
@@ -183,6 +631,20 @@ func (o *Options) getMetadata() string {
 	return ""
 }
 
+func (o *Options) getMetadataMap() map[string]string {
+	if o != nil {
+		return o.MetadataMap
+	}
+	return nil
+}
+
+func (o *Options) getTokenGenerator(fallback func() (string, error)) func() (string, error) {
+	if o != nil && o.TokenGenerator != nil {
+		return o.TokenGenerator
+	}
+	return fallback
+}
+
 func (o *Options) getContext() context.Context {
 	if o != nil && o.Context != nil {
 		return o.Context
@@ -190,6 +652,50 @@ func (o *Options) getContext() context.Context {
 	return context.Background()
 }
 
+func (o *Options) getWatchdogContext() context.Context {
+	if o != nil && o.WatchdogContext != nil {
+		return o.WatchdogContext
+	}
+	return context.Background()
+}
+
+func (o *Options) getObtainTimeout(ttl time.Duration) time.Duration {
+	if o != nil && o.ObtainTimeout > 0 {
+		return o.ObtainTimeout
+	}
+	return ttl
+}
+
+func (o *Options) getMaxRefreshes() int {
+	if o != nil {
+		return o.MaxRefreshes
+	}
+	return 0
+}
+
+func (o *Options) getMaxExtension() time.Duration {
+	if o != nil {
+		return o.MaxExtension
+	}
+	return 0
+}
+
+func (o *Options) getAutoRefresh() bool {
+	if o != nil {
+		return o.AutoRefresh
+	}
+	return false
+}
+
+func (o *Options) getKind() lockKind {
+	if o != nil && o.Reentrant {
+		return reentrantLock
+	} else if o != nil && o.Fence {
+		return fencedLock
+	}
+	return exclusiveLock
+}
+
 func (o *Options) getRetryStrategy() RetryStrategy {
 	if o != nil && o.RetryStrategy != nil {
 		return o.RetryStrategy
@@ -240,6 +746,135 @@ func (r *limitedRetry) NextBackoff() time.Duration {
 	return r.s.NextBackoff()
 }
 
+// RetryAttempt describes the failed attempts of an obtain.
+type RetryAttempt struct {
+	// Attempt is the number of failed attempts, starting at 1.
+	Attempt int
+	// Elapsed is the time elapsed since the first attempt.
+	Elapsed time.Duration
+}
+
+// AttemptAwareRetryStrategy is a RetryStrategy which observes the failed
+// attempts, e.g. to implement deadline-aware policies. Obtain calls Backoff
+// instead of NextBackoff.
+type AttemptAwareRetryStrategy interface {
+	RetryStrategy
+
+	// Backoff returns the next backoff duration after the given attempt.
+	Backoff(a RetryAttempt) time.Duration
+}
+
+type retryFunc struct {
+	fn    func(RetryAttempt) time.Duration
+	cnt   int
+	start time.Time
+}
+
+// RetryFunc creates an AttemptAwareRetryStrategy from fn. Returning a backoff
+// less than 1 stops retrying.
+func RetryFunc(fn func(a RetryAttempt) time.Duration) RetryStrategy {
+	return &retryFunc{fn: fn}
+}
+
+func (r *retryFunc) NextBackoff() time.Duration {
+	if r.cnt++; r.cnt == 1 {
+		r.start = time.Now()
+	}
+	return r.fn(RetryAttempt{Attempt: r.cnt, Elapsed: time.Since(r.start)})
+}
+
+func (r *retryFunc) Backoff(a RetryAttempt) time.Duration {
+	return r.fn(a)
+}
+
+// LimitElapsed stops retrying once the next backoff would end after max has
+// elapsed since the first attempt.
+func LimitElapsed(s RetryStrategy, max time.Duration) RetryStrategy {
+	return RetryFunc(func(a RetryAttempt) time.Duration {
+		d := nextBackoff(s, a)
+		if a.Elapsed+d > max {
+			return 0
+		}
+		return d
+	})
+}
+
+// nextBackoff returns the next backoff of s after the given attempt.
+func nextBackoff(s RetryStrategy, a RetryAttempt) time.Duration {
+	if aware, ok := s.(AttemptAwareRetryStrategy); ok {
+		return aware.Backoff(a)
+	}
+	return s.NextBackoff()
+}
+
+type fullJitter struct {
+	s RetryStrategy
+}
+
+// FullJitter picks a random backoff between zero and the backoff of s, which
+// spreads the retries of competing clients.
+func FullJitter(s RetryStrategy) RetryStrategy {
+	return &fullJitter{s: s}
+}
+
+func (r *fullJitter) NextBackoff() time.Duration {
+	return r.jitter(r.s.NextBackoff())
+}
+
+func (r *fullJitter) Backoff(a RetryAttempt) time.Duration {
+	return r.jitter(nextBackoff(r.s, a))
+}
+
+func (r *fullJitter) jitter(d time.Duration) time.Duration {
+	if d < 1 {
+		return d
+	}
+	return time.Duration(mrand.Int63n(int64(d))) + 1
+}
+
+type jitteredBackoff struct {
+	exp *exponentialBackoff
+}
+
+// ExponentialBackoffWithJitter is an ExponentialBackoff which picks a random
+// backoff between half and the full exponential backoff, which spreads the
+// retries of competing clients.
+func ExponentialBackoffWithJitter(min, max time.Duration) RetryStrategy {
+	return &jitteredBackoff{exp: &exponentialBackoff{min: min, max: max}}
+}
+
+// blockingBackoff is used by ObtainBlocking, it never gives up.
+func blockingBackoff() RetryStrategy {
+	return ExponentialBackoffWithJitter(16*time.Millisecond, time.Second)
+}
+
+func (r *jitteredBackoff) NextBackoff() time.Duration {
+	d := r.exp.NextBackoff()
+	return d/2 + time.Duration(mrand.Int63n(int64(d/2)+1))
+}
+
+type decorrelatedJitter struct {
+	base, max, prev time.Duration
+}
+
+// DecorrelatedJitter picks a random backoff between base and three times the
+// previous backoff, capped at max.
+func DecorrelatedJitter(base, max time.Duration) RetryStrategy {
+	return &decorrelatedJitter{base: base, max: max, prev: base}
+}
+
+func (r *decorrelatedJitter) NextBackoff() time.Duration {
+	d := r.base
+	if upper := 3 * r.prev; upper > r.base {
+		d += time.Duration(mrand.Int63n(int64(upper - r.base)))
+	}
+	if r.max != 0 && d > r.max {
+		d = r.max
+	}
+	r.prev = d
+	return d
+}
+
 type exponentialBackoff struct {
 	cnt uint
 
@@ -0,0 +1,125 @@
+package redislock
+
+import "context"
+
+// lua scripts for reentrant locks, run through the Scripter interface. A
+// reentrant lock is stored as hash of owner ID to hold count.
+const (
+	LuaReentrantObtainScript  = `if KEYS[2] and redis.call("exists", KEYS[2]) == 1 then return -1 end local t = redis.call("type", KEYS[1]).ok if t ~= "none" and (t ~= "hash" or redis.call("hexists", KEYS[1], ARGV[1]) == 0) then return 0 end local n = redis.call("hincrby", KEYS[1], ARGV[1], 1) if redis.call("pttl", KEYS[1]) < tonumber(ARGV[2]) then redis.call("pexpire", KEYS[1], ARGV[2]) end return n`
+	LuaReentrantRefreshScript = `if redis.call("type", KEYS[1]).ok == "hash" and redis.call("hexists", KEYS[1], ARGV[1]) == 1 then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	LuaReentrantReleaseScript = `if redis.call("type", KEYS[1]).ok ~= "hash" or redis.call("hexists", KEYS[1], ARGV[1]) == 0 then return -1 end local n = redis.call("hincrby", KEYS[1], ARGV[1], -1) if n <= 0 then redis.call("del", KEYS[1]) end return n`
+	LuaReentrantPTTLScript    = `if redis.call("type", KEYS[1]).ok == "hash" and redis.call("hexists", KEYS[1], ARGV[1]) == 1 then return redis.call("pttl", KEYS[1]) else return -3 end`
+)
+
+var (
+	// ReentrantObtainScript increments the hold count of owner ARGV[1] in
+	// KEYS[1] unless it is held by another owner, and extends its TTL to at
+	// least ARGV[2] milliseconds. Returns the hold count if obtained, 0 if not
+	// and -1 if the optional pause key KEYS[2] exists.
+	ReentrantObtainScript = &Script{Name: "reentrant-obtain", Source: LuaReentrantObtainScript}
+
+	// ReentrantRefreshScript sets the TTL of KEYS[1] to ARGV[2] milliseconds
+	// if it is held by owner ARGV[1]. Returns 1 if refreshed, 0 otherwise.
+	ReentrantRefreshScript = &Script{Name: "reentrant-refresh", Source: LuaReentrantRefreshScript}
+
+	// ReentrantReleaseScript decrements the hold count of owner ARGV[1] in
+	// KEYS[1] and deletes the key once it reaches zero. Returns the remaining
+	// hold count or -1 if not held by the owner.
+	ReentrantReleaseScript = &Script{Name: "reentrant-release", Source: LuaReentrantReleaseScript}
+
+	// ReentrantPTTLScript returns the TTL of KEYS[1] in milliseconds if it is
+	// held by owner ARGV[1], -3 otherwise.
+	ReentrantPTTLScript = &Script{Name: "reentrant-pttl", Source: LuaReentrantPTTLScript}
+)
+
+// ownerContextKey is the context key of the owner set by ContextWithOwner.
+type ownerContextKey struct{}
+
+// ContextWithOwner returns a copy of ctx which scopes the ownership of
+// reentrant locks obtained with it as Options.Context to owner, e.g. the ID of
+// a request or job. Only obtains with the same owner re-enter each other's
+// locks, while other goroutines of the same Client are excluded. Owners are
+// scoped to the owner ID of the client, see WithOwnerID.
+func ContextWithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerContextKey{}, owner)
+}
+
+// reentrantOwner returns the owner of reentrant locks obtained with ctx.
+func (c *Client) reentrantOwner(ctx context.Context) (string, error) {
+	id, err := c.ownerID()
+	if err != nil {
+		return "", err
+	}
+	if owner, ok := ctx.Value(ownerContextKey{}).(string); ok && owner != "" {
+		return id + ":" + owner, nil
+	}
+	return id, nil
+}
+
+// ownerID returns the ID identifying the client as owner of reentrant locks,
+// a random ID unless set through WithOwnerID.
+func (c *Client) ownerID() (string, error) {
+	c.ownerOnce.Do(func() {
+		if c.owner == "" {
+			c.owner, c.ownerErr = c.randomToken()
+		}
+	})
+	return c.owner, c.ownerErr
+}
+
+func (c *Client) obtainReentrant(key, owner, ttl string) (bool, error) {
+	keys := []string{key}
+	if c.pauseKey != "" {
+		keys = append(keys, c.pauseKey)
+	}
+
+	res, err := c.runScript(ReentrantObtainScript, keys, owner, ttl)
+	if err != nil {
+		return false, err
+	} else if res == int64(-1) {
+		return false, ErrLockingPaused
+	}
+	n, _ := res.(int64)
+	return n > 0, nil
+}
+
+func (l *Lock) refreshReentrant(ttl string) error {
+	res, err := l.client.runScript(ReentrantRefreshScript, []string{l.key}, l.Token(), ttl)
+	if err != nil {
+		return err
+	} else if res != int64(1) {
+		return ErrNotObtained
+	}
+	return nil
+}
+
+func (l *Lock) releaseReentrant() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// every Lock owns a single hold
+	if l.released {
+		return ErrLockNotHeld
+	}
+
+	res, err := l.client.runScript(ReentrantReleaseScript, []string{l.key}, l.Token())
+	if err != nil {
+		return err
+	}
+	l.released = true
+	if res == int64(-1) {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) ttlReentrant() (int64, error) {
+	res, err := l.client.runScript(ReentrantPTTLScript, []string{l.key}, l.Token())
+	if err != nil {
+		return 0, err
+	} else if res == int64(-3) {
+		return 0, ErrLockNotHeld
+	}
+	n, _ := res.(int64)
+	return n, nil
+}
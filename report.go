@@ -0,0 +1,128 @@
+package redislock
+
+import (
+	"sort"
+	"time"
+)
+
+// minHeldPrune is the minimum number of locks tracked before expired ones are
+// pruned.
+const minHeldPrune = 64
+
+// HeldLock summarises a lock held by a Client.
+type HeldLock struct {
+	// Key is the key the lock was obtained with.
+	Key string
+
+	// RedisKey is the redis key of the lock.
+	RedisKey string
+
+	// Age is the time elapsed since the lock was obtained.
+	Age time.Duration
+
+	// TTL is the remaining TTL as last set by this client, zero if the key
+	// does not expire. It is computed locally and does not query redis.
+	TTL time.Duration
+
+	// Refreshes is the number of successful refreshes.
+	Refreshes int
+}
+
+// WithHeldLocksReport invokes fn every interval with a summary of all locks
+// held by the client. Reporting stops when the client is closed.
+func WithHeldLocksReport(interval time.Duration, fn func([]HeldLock)) ClientOption {
+	return func(c *Client) {
+		c.reportInterval = interval
+		c.reportFunc = fn
+	}
+}
+
+// HeldLocks returns a summary of all locks currently held by the client,
+// ordered by age. Locks which have expired according to their last known TTL
+// are no longer considered held.
+func (c *Client) HeldLocks() []HeldLock {
+	now := time.Now()
+
+	c.heldMu.Lock()
+	locks := make([]*Lock, 0, len(c.held))
+	for l := range c.held {
+		locks = append(locks, l)
+	}
+	c.heldMu.Unlock()
+
+	res := make([]HeldLock, 0, len(locks))
+	for _, l := range locks {
+		l.mu.Lock()
+		expiresAt, refreshes := l.expiresAt, l.refreshes
+		l.mu.Unlock()
+
+		var ttl time.Duration
+		if !expiresAt.IsZero() {
+			if ttl = expiresAt.Sub(now); ttl <= 0 {
+				c.untrack(l)
+				continue
+			}
+		}
+		res = append(res, HeldLock{
+			Key:       l.Key(),
+			RedisKey:  l.key,
+			Age:       now.Sub(l.obtainedAt),
+			TTL:       ttl,
+			Refreshes: refreshes,
+		})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Age > res[j].Age })
+	return res
+}
+
+// track adds l to the locks held by the client. Locks left to expire are
+// pruned whenever the number of tracked locks doubled since the last pruning,
+// so they do not accumulate without calls to HeldLocks.
+func (c *Client) track(l *Lock) {
+	now := time.Now()
+
+	c.heldMu.Lock()
+	defer c.heldMu.Unlock()
+
+	if len(c.held) >= c.heldPruneAt {
+		for h := range c.held {
+			// skip locks busy refreshing, they are not left to expire
+			if !h.mu.TryLock() {
+				continue
+			}
+			expired := !h.expiresAt.IsZero() && !now.Before(h.expiresAt)
+			h.mu.Unlock()
+
+			if expired {
+				delete(c.held, h)
+			}
+		}
+		c.heldPruneAt = 2*len(c.held) + minHeldPrune
+	}
+	c.held[l] = struct{}{}
+}
+
+func (c *Client) untrack(l *Lock) {
+	c.heldMu.Lock()
+	if _, ok := c.held[l]; ok {
+		delete(c.held, l)
+		close(c.heldChanged)
+		c.heldChanged = make(chan struct{})
+	}
+	c.heldMu.Unlock()
+}
+
+func (c *Client) report() {
+	ticker := time.NewTicker(c.reportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closed:
+			return
+		case <-ticker.C:
+			c.reportFunc(c.HeldLocks())
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package redislock
+
+import "time"
+
+// lua scripts for read/write locks, run through the Scripter interface. A
+// read/write lock is stored as sorted set of holders, prefixed by r: for
+// readers and w: for the writer, scored by their expiry in milliseconds of
+// the redis server time. Expired holders are removed before every operation.
+const (
+	luaRWPrelude         = `if redis.replicate_commands then redis.replicate_commands() end local t = redis.call("time") local now = t[1] * 1000 + math.floor(t[2] / 1000) local ty = redis.call("type", KEYS[1]).ok if ty == "zset" then redis.call("zremrangebyscore", KEYS[1], "-inf", now) end `
+	LuaObtainReadScript  = luaRWPrelude + `if KEYS[2] and redis.call("exists", KEYS[2]) == 1 then return -1 end if ty ~= "none" and ty ~= "zset" then return 0 end local first = redis.call("zrange", KEYS[1], 0, 0)[1] if first and string.sub(first, 1, 2) == "w:" then return 0 end redis.call("zadd", KEYS[1], now + ARGV[2], "r:" .. ARGV[1]) if redis.call("pttl", KEYS[1]) < tonumber(ARGV[2]) then redis.call("pexpire", KEYS[1], ARGV[2]) end return 1`
+	LuaObtainWriteScript = luaRWPrelude + `if KEYS[2] and redis.call("exists", KEYS[2]) == 1 then return -1 end if ty ~= "none" and ty ~= "zset" then return 0 end if redis.call("zcard", KEYS[1]) > 0 then return 0 end redis.call("zadd", KEYS[1], now + ARGV[2], "w:" .. ARGV[1]) redis.call("pexpire", KEYS[1], ARGV[2]) return 1`
+	LuaRWRefreshScript   = luaRWPrelude + `if ty ~= "zset" or not redis.call("zscore", KEYS[1], ARGV[1]) then return 0 end redis.call("zadd", KEYS[1], now + ARGV[2], ARGV[1]) if redis.call("pttl", KEYS[1]) < tonumber(ARGV[2]) then redis.call("pexpire", KEYS[1], ARGV[2]) end return 1`
+	LuaRWReleaseScript   = luaRWPrelude + `if ty ~= "zset" then return 0 end local n = redis.call("zrem", KEYS[1], ARGV[1]) if redis.call("zcard", KEYS[1]) == 0 then redis.call("del", KEYS[1]) end return n`
+	LuaRWPTTLScript      = luaRWPrelude + `if ty ~= "zset" then return -3 end local s = redis.call("zscore", KEYS[1], ARGV[1]) if not s then return -3 end return tonumber(s) - now`
+)
+
+// sorted set member prefixes of readers and writers
+const readPrefix, writePrefix = "r:", "w:"
+
+var (
+	// ObtainReadScript adds reader ARGV[1] to KEYS[1] for ARGV[2]
+	// milliseconds unless it is held by a writer. Returns 1 if obtained, 0 if
+	// not and -1 if the optional pause key KEYS[2] exists.
+	ObtainReadScript = &Script{Name: "obtain-read", Source: LuaObtainReadScript}
+
+	// ObtainWriteScript adds writer ARGV[1] to KEYS[1] for ARGV[2]
+	// milliseconds unless it is held by anyone. Returns 1 if obtained, 0 if
+	// not and -1 if the optional pause key KEYS[2] exists.
+	ObtainWriteScript = &Script{Name: "obtain-write", Source: LuaObtainWriteScript}
+
+	// RWRefreshScript extends the hold of member ARGV[1], i.e. the prefixed
+	// reader or writer or the semaphore holder, of KEYS[1] to ARGV[2]
+	// milliseconds. Returns 1 if refreshed, 0 if not held.
+	RWRefreshScript = &Script{Name: "rw-refresh", Source: LuaRWRefreshScript}
+
+	// RWReleaseScript removes member ARGV[1] from KEYS[1]. Returns 1 if
+	// released, 0 if not held.
+	RWReleaseScript = &Script{Name: "rw-release", Source: LuaRWReleaseScript}
+
+	// RWPTTLScript returns the remaining hold of member ARGV[1] of KEYS[1] in
+	// milliseconds, -3 if not held.
+	RWPTTLScript = &Script{Name: "rw-pttl", Source: LuaRWPTTLScript}
+)
+
+// ObtainRead tries to obtain a shared read lock using a key with the given
+// TTL. Any number of readers may hold the lock at the same time, as long as
+// no writer holds it. Read/write locks require a backend implementing
+// Scripter and redis 3.2+. Do not mix them with other locks on the same key.
+// The Reentrant option is ignored.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainRead(key string, ttl time.Duration, opt *Options) (*Lock, error) {
+	return c.obtainLock(readLock, 0, key, ttl, opt, opt.getRetryStrategy(), time.Now().Add(opt.getObtainTimeout(ttl)))
+}
+
+// ObtainWrite tries to obtain an exclusive write lock using a key with the
+// given TTL. The lock is only obtained while neither readers nor another
+// writer hold it, see ObtainRead.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainWrite(key string, ttl time.Duration, opt *Options) (*Lock, error) {
+	return c.obtainLock(writeLock, 0, key, ttl, opt, opt.getRetryStrategy(), time.Now().Add(opt.getObtainTimeout(ttl)))
+}
+
+func (c *Client) obtainRW(kind lockKind, key, value, ttl string) (bool, error) {
+	keys := []string{key}
+	if c.pauseKey != "" {
+		keys = append(keys, c.pauseKey)
+	}
+
+	script := ObtainReadScript
+	if kind == writeLock {
+		script = ObtainWriteScript
+	}
+
+	res, err := c.runScript(script, keys, value, ttl)
+	if err != nil {
+		return false, err
+	} else if res == int64(-1) {
+		return false, ErrLockingPaused
+	}
+	return res == int64(1), nil
+}
+
+// member returns the sorted set member of a read/write lock or semaphore.
+func (l *Lock) member() string {
+	switch l.kind {
+	case readLock:
+		return readPrefix + l.value
+	case writeLock:
+		return writePrefix + l.value
+	}
+	return l.value
+}
+
+func (l *Lock) refreshRW(ttl string) error {
+	res, err := l.client.runScript(RWRefreshScript, []string{l.key}, l.member(), ttl)
+	if err != nil {
+		return err
+	} else if res != int64(1) {
+		return ErrNotObtained
+	}
+	return nil
+}
+
+func (l *Lock) releaseRW() error {
+	res, err := l.client.runScript(RWReleaseScript, []string{l.key}, l.member())
+	if err != nil {
+		return err
+	} else if res != int64(1) {
+		return ErrLockNotHeld
+	}
+	return nil
+}
+
+func (l *Lock) ttlRW() (int64, error) {
+	res, err := l.client.runScript(RWPTTLScript, []string{l.key}, l.member())
+	if err != nil {
+		return 0, err
+	} else if res == int64(-3) {
+		return 0, ErrLockNotHeld
+	}
+	n, _ := res.(int64)
+	return n, nil
+}
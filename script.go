@@ -0,0 +1,37 @@
+package redislock
+
+import (
+	"strconv"
+	"time"
+)
+
+// Script is a lua script used by the optional lock operations. Redis backends
+// evaluate Source, other backends may implement the script natively and
+// dispatch on Name.
+type Script struct {
+	Name   string
+	Source string
+}
+
+// Scripter is an optional interface of backends which can run the package's
+// scripts atomically. It is required by the lock operations beyond the Backend
+// interface. Implementations must return integer replies as int64, bulk
+// replies as string, nil replies as nil and array replies as []interface{}.
+type Scripter interface {
+	RunScript(script *Script, keys []string, args ...string) (interface{}, error)
+}
+
+// runScript runs script through the backend.
+// May return ErrNotSupported if the backend does not implement Scripter.
+func (c *Client) runScript(script *Script, keys []string, args ...string) (interface{}, error) {
+	scripter, ok := c.backend.(Scripter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	return scripter.RunScript(script, keys, args...)
+}
+
+// formatMillis formats d as decimal milliseconds, as expected by the scripts.
+func formatMillis(d time.Duration) string {
+	return strconv.FormatInt(int64(d/time.Millisecond), 10)
+}
@@ -0,0 +1,49 @@
+package redislock
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// lua script for semaphores, run through the Scripter interface. A semaphore
+// is stored like a read/write lock, as sorted set of holders scored by their
+// expiry, and maintained by the read/write lock scripts once obtained.
+const LuaObtainSemaphoreScript = luaRWPrelude + `if KEYS[2] and redis.call("exists", KEYS[2]) == 1 then return -1 end if ty ~= "none" and ty ~= "zset" then return 0 end if redis.call("zcard", KEYS[1]) >= tonumber(ARGV[3]) then return 0 end redis.call("zadd", KEYS[1], now + ARGV[2], ARGV[1]) if redis.call("pttl", KEYS[1]) < tonumber(ARGV[2]) then redis.call("pexpire", KEYS[1], ARGV[2]) end return 1`
+
+// ObtainSemaphoreScript adds holder ARGV[1] to KEYS[1] for ARGV[2]
+// milliseconds unless it is held by ARGV[3] holders. Returns 1 if obtained, 0
+// if not and -1 if the optional pause key KEYS[2] exists.
+var ObtainSemaphoreScript = &Script{Name: "obtain-semaphore", Source: LuaObtainSemaphoreScript}
+
+var errInvalidLimit = errors.New("redislock: semaphore limit must be positive")
+
+// ObtainSemaphore tries to obtain one of limit slots of a counting semaphore
+// using a key with the given TTL. Up to limit holders may hold the key at the
+// same time, each with its own TTL, e.g. to bound the concurrent access to a
+// rate-limited API across processes. Semaphores require a backend implementing
+// Scripter and redis 3.2+. Do not mix them with other locks on the same key
+// and use the same limit for every obtain of a key. The Reentrant and Fence
+// options are ignored.
+// May return ErrNotObtained if not successful.
+func (c *Client) ObtainSemaphore(key string, limit int, ttl time.Duration, opt *Options) (*Lock, error) {
+	if limit < 1 {
+		return nil, errInvalidLimit
+	}
+	return c.obtainLock(semaphoreLock, limit, key, ttl, opt, opt.getRetryStrategy(), time.Now().Add(opt.getObtainTimeout(ttl)))
+}
+
+func (c *Client) obtainSemaphore(key, value, ttl string, limit int) (bool, error) {
+	keys := []string{key}
+	if c.pauseKey != "" {
+		keys = append(keys, c.pauseKey)
+	}
+
+	res, err := c.runScript(ObtainSemaphoreScript, keys, value, ttl, strconv.Itoa(limit))
+	if err != nil {
+		return false, err
+	} else if res == int64(-1) {
+		return false, ErrLockingPaused
+	}
+	return res == int64(1), nil
+}
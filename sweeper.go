@@ -0,0 +1,136 @@
+package redislock
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	errNoMatch    = errors.New("redislock: sweep requires Match")
+	errNoOrphaned = errors.New("redislock: sweep requires Orphaned")
+)
+
+// Scanner is an optional interface of backends which can enumerate locks.
+// It is required by Client.Sweep.
+type Scanner interface {
+	// Scan returns all keys matching the glob-style pattern. An empty
+	// pattern matches no keys.
+	Scan(match string) ([]string, error)
+
+	// Get returns the value of key or an empty string if key does not exist
+	// or does not hold a string, e.g. the hash of a reentrant lock.
+	Get(key string) (string, error)
+}
+
+// SweptLock describes a lock found by a sweep.
+type SweptLock struct {
+	// Key is the key the lock was obtained with.
+	Key string
+	// RedisKey is the redis key of the lock.
+	RedisKey string
+	// Token is the token of the lock holder.
+	Token string
+	// Metadata is the metadata the lock was obtained with.
+	Metadata string
+	// MetadataMap is the metadata map the lock was obtained with.
+	MetadataMap map[string]string
+}
+
+// SweepOptions configure a sweep for orphaned locks.
+type SweepOptions struct {
+	// Match is the glob-style pattern of the lock keys to scan within the
+	// namespace of the client, like in Client.Keys, e.g. "jobs:*". Use "*" to
+	// scan all locks of the namespace.
+	// Required.
+	Match string
+
+	// Orphaned reports whether the owner of a lock is dead, e.g. by checking
+	// a heartbeat or the owner instance recorded in its metadata against a
+	// service registry. Locks are only expired if Orphaned returns true.
+	// Required.
+	Orphaned func(lock SweptLock) (bool, error)
+
+	// DryRun reports orphaned locks without expiring them.
+	DryRun bool
+
+	// OnOrphan, if set, is called for every orphaned lock with the result of
+	// expiring it, which is always nil on a dry run.
+	OnOrphan func(lock SweptLock, err error)
+}
+
+// Sweep scans the locks within the namespace of the client matching opt.Match
+// and expires those reported as orphaned. A lock is only expired if it is still held by the same token,
+// which makes it safe against concurrent re-obtains. Reentrant, read/write
+// and semaphore locks as well as fence counters and the control key of Pause
+// are skipped. It returns the orphaned locks found. May
+// return ErrNotSupported if the backend does not implement Scanner.
+func (c *Client) Sweep(opt SweepOptions) ([]SweptLock, error) {
+	if opt.Match == "" {
+		return nil, errNoMatch
+	} else if opt.Orphaned == nil {
+		return nil, errNoOrphaned
+	}
+	scanner, ok := c.backend.(Scanner)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	keys, err := scanner.Scan(c.scanPattern(opt.Match))
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []SweptLock
+	for _, key := range keys {
+		name, ok := c.logicalKey(key)
+		if !ok || c.reserved(key) {
+			continue
+		}
+
+		value, err := scanner.Get(key)
+		if err != nil {
+			return orphans, err
+		} else if value == "" {
+			continue
+		}
+
+		v := decodeValue(value)
+		lock := SweptLock{Key: name, RedisKey: key, Token: v.Token, Metadata: v.Metadata, MetadataMap: v.MetadataMap}
+		if orphaned, err := opt.Orphaned(lock); err != nil {
+			return orphans, err
+		} else if !orphaned {
+			continue
+		}
+		orphans = append(orphans, lock)
+
+		var expireErr error
+		if !opt.DryRun {
+			expireErr = c.backend.Release(key, value)
+			c.debugf(DebugInfo, "swept key=%s err=%v", key, expireErr)
+		}
+		if opt.OnOrphan != nil {
+			opt.OnOrphan(lock, expireErr)
+		}
+	}
+	return orphans, nil
+}
+
+// RunSweeper sweeps every interval until ctx is cancelled. Errors of
+// individual sweeps are passed to onError, if set, and do not stop the
+// sweeper. It always returns ctx.Err().
+func (c *Client) RunSweeper(ctx context.Context, interval time.Duration, opt SweepOptions, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if _, err := c.Sweep(opt); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
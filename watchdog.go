@@ -0,0 +1,114 @@
+package redislock
+
+import (
+	"context"
+	"time"
+)
+
+// watchdog refreshes an auto refreshed lock at a third of its TTL.
+type watchdog struct {
+	ctx  context.Context
+	ttl  time.Duration
+	stop chan struct{}
+	done chan struct{}
+	lost chan struct{}
+	err  error
+}
+
+func (l *Lock) startWatchdog(ctx context.Context, ttl time.Duration) {
+	l.watchdog = &watchdog{
+		ctx:  ctx,
+		ttl:  ttl,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+		lost: make(chan struct{}),
+	}
+	go l.watch()
+}
+
+// stopWatchdog stops auto refreshing and waits for an in-flight refresh.
+func (l *Lock) stopWatchdog() {
+	if l.watchdog == nil {
+		return
+	}
+
+	l.stopOnce.Do(func() { close(l.watchdog.stop) })
+	<-l.watchdog.done
+}
+
+// watch refreshes the lock until it is released, the context is cancelled,
+// the client is closed or a refresh fails.
+func (l *Lock) watch() {
+	w := l.watchdog
+	defer close(w.done)
+
+	interval := w.ttl / 3
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.ctx.Done():
+			l.client.debugf(DebugInfo, "auto refresh stopped key=%s err=%v", l.key, w.ctx.Err())
+			return
+		case <-l.client.closed:
+			return
+		case <-ticker.C:
+		}
+
+		err := l.refresh(w.ttl)
+		l.client.debugf(DebugVerbose, "auto refresh key=%s ttl=%s err=%v", l.key, w.ttl, err)
+		l.client.hooks.OnRefresh(l.Key(), w.ttl, err)
+		if err == nil {
+			continue
+		}
+
+		// retry transient errors for as long as the lock is still valid
+		if err != ErrNotObtained && err != ErrRefreshLimit {
+			l.mu.Lock()
+			expiresAt := l.expiresAt
+			l.mu.Unlock()
+
+			if time.Now().Add(interval).Before(expiresAt) {
+				continue
+			}
+		}
+
+		l.client.debugf(DebugInfo, "lock lost key=%s err=%v", l.key, err)
+		l.client.hooks.OnLost(l.Key(), err)
+		w.err = err
+		close(w.lost)
+		l.client.untrack(l)
+		return
+	}
+}
+
+// Lost returns a channel which is closed when an auto refresh fails and the
+// lock is no longer held. It returns nil unless the lock was obtained with
+// AutoRefresh.
+func (l *Lock) Lost() <-chan struct{} {
+	if l.watchdog == nil {
+		return nil
+	}
+	return l.watchdog.lost
+}
+
+// Err returns the error of the refresh which lost the lock once Lost is
+// closed, nil otherwise.
+func (l *Lock) Err() error {
+	if l.watchdog == nil {
+		return nil
+	}
+
+	select {
+	case <-l.watchdog.lost:
+		return l.watchdog.err
+	default:
+		return nil
+	}
+}